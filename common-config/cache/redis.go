@@ -12,6 +12,9 @@ import (
 	"github.com/gomodule/redigo/redis"
 )
 
+// log 组件日志, 可通过 logger.SetLevelFor("redisapi", ...) 单独调整级别
+var log = logger.Named("redisapi")
+
 // RedisInterface redis interface for all service
 type RedisInterface interface {
 	Get(key string) (value string, err error)
@@ -118,7 +121,7 @@ func (api *RedisApi) Get(key string) (string, error) {
 	defer func(redisConn redis.Conn) {
 		err := redisConn.Close()
 		if err != nil {
-			logger.Errorf("redis get catch err=%#v", err)
+			log.Errorf("redis get catch err=%#v", err)
 		}
 	}(redisConn)
 	r, err := redisConn.Do("GET", key)
@@ -166,7 +169,7 @@ func (api *RedisApi) Expire(key string, seconds uint64) error {
 	defer func(redisConn redis.Conn) {
 		err := redisConn.Close()
 		if err != nil {
-			logger.Errorf("redis expire catch %#v", err)
+			log.Errorf("redis expire catch %#v", err)
 		}
 	}(redisConn)
 	retValue, err := redis.Int(redisConn.Do("EXPIRE", key, seconds))
@@ -203,10 +206,10 @@ func (api *RedisApi) GenerateLock(ctx context.Context, SeizeTimeOut time.Duratio
 			err := api.SetNxEx(key, uuid, int64(lockExpireTime.Seconds()))
 			if err == nil {
 				go api.MonitorLock(ctx, key, int64(lockExpireTime.Seconds()))
-				logger.Infof("get key:%v,uuid:%v", key, uuid)
+				log.Infof("get key:%v,uuid:%v", key, uuid)
 				return nil
 			} else {
-				logger.Infof("can't get key")
+				log.Infof("can't get key")
 				// 如果当前moment被阻塞 则自旋200毫秒等待，让P去处理其他G
 				time.Sleep(200 * time.Millisecond)
 			}
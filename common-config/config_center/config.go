@@ -0,0 +1,369 @@
+package config_center
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+)
+
+// DefaultWatchRetryInterval is the interval a source is watched again after an error.
+const DefaultWatchRetryInterval = 3 * time.Second
+
+var (
+	// ErrWatcherStopped is returned by Watcher.Next once the watcher is stopped.
+	ErrWatcherStopped = errors.New("config: watcher stopped")
+	// ErrNotFound is returned when the key has no value.
+	ErrNotFound = errors.New("config: key not found")
+)
+
+// Observer is called with the new value of a watched key.
+type Observer func(key string, value interface{})
+
+// Option is config option.
+type Option func(c *Config)
+
+// WithSource with config sources, later sources override the earlier ones.
+func WithSource(s ...Source) Option {
+	return func(c *Config) {
+		c.sources = append(c.sources, s...)
+	}
+}
+
+// Config merges the sources and notifies the observers of their changes.
+type Config struct {
+	sources   []Source
+	mu        sync.RWMutex
+	kvs       [][]*KeyValue
+	values    map[string]interface{}
+	observers map[string][]Observer
+	watchers  []Watcher
+	stopped   chan struct{}
+	once      sync.Once
+}
+
+// New create a config of sources.
+func New(opts ...Option) *Config {
+	c := &Config{
+		values:    make(map[string]interface{}),
+		observers: make(map[string][]Observer),
+		stopped:   make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Load loads every source and starts watching them.
+func (c *Config) Load() error {
+	kvs := make([][]*KeyValue, len(c.sources))
+	for i, s := range c.sources {
+		list, err := s.Load()
+		if err != nil {
+			return err
+		}
+		kvs[i] = list
+	}
+	values, err := mergeKeyValues(kvs)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.kvs = kvs
+	c.values = values
+	c.mu.Unlock()
+	for i, s := range c.sources {
+		w, err := s.Watch()
+		if err != nil {
+			_ = c.Close()
+			return err
+		}
+		c.mu.Lock()
+		c.watchers = append(c.watchers, w)
+		c.mu.Unlock()
+		go c.watch(i, s, w)
+	}
+	return nil
+}
+
+// Scan unmarshal the merged config into v through its json tags, the values are converted
+// to the types of the fields like convert.
+func (c *Config) Scan(v interface{}) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return convert(c.values, v)
+}
+
+// Value returns the value of a dot separated key, e.g. log_config.level,
+// the empty key is the whole config.
+func (c *Config) Value(key string) (interface{}, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := lookup(c.values, key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+// Watch registers an observer called whenever the value of key changes.
+func (c *Config) Watch(key string, o Observer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observers[key] = append(c.observers[key], o)
+}
+
+// OnChange registers a typed callback called with the new value of key decoded into T.
+func OnChange[T any](c *Config, key string, fn func(value T)) {
+	c.Watch(key, func(key string, value interface{}) {
+		var v T
+		if err := convert(value, &v); err != nil {
+			logger.Errorf("[config] decode %s into %T catch err=%v", key, v, err)
+			return
+		}
+		fn(v)
+	})
+}
+
+// Get returns the value of key decoded into T.
+func Get[T any](c *Config, key string) (T, error) {
+	var v T
+	value, err := c.Value(key)
+	if err != nil {
+		return v, err
+	}
+	err = convert(value, &v)
+	return v, err
+}
+
+// Close stops watching the sources.
+func (c *Config) Close() error {
+	c.once.Do(func() {
+		close(c.stopped)
+		c.mu.Lock()
+		watchers := c.watchers
+		c.mu.Unlock()
+		for _, w := range watchers {
+			if err := w.Stop(); err != nil {
+				logger.Errorf("[config] stop watcher catch err=%v", err)
+			}
+		}
+	})
+	return nil
+}
+
+// watch applies the changes of the i-th source until the config is closed.
+func (c *Config) watch(i int, s Source, w Watcher) {
+	for {
+		list, err := w.Next()
+		if errors.Is(err, ErrWatcherStopped) {
+			return
+		}
+		if err != nil {
+			logger.Errorf("[config] watch source %T catch err=%v", s, err)
+			select {
+			case <-c.stopped:
+				return
+			case <-time.After(DefaultWatchRetryInterval):
+			}
+			continue
+		}
+		if err := c.apply(i, list); err != nil {
+			logger.Errorf("[config] apply source %T catch err=%v", s, err)
+		}
+	}
+}
+
+// apply replaces the contents of the i-th source and notifies the changed keys.
+func (c *Config) apply(i int, list []*KeyValue) error {
+	c.mu.Lock()
+	kvs := append([][]*KeyValue(nil), c.kvs...)
+	kvs[i] = list
+	values, err := mergeKeyValues(kvs)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	old := c.values
+	c.kvs = kvs
+	c.values = values
+	type change struct {
+		key       string
+		value     interface{}
+		observers []Observer
+	}
+	var changes []change
+	for key, observers := range c.observers {
+		ov, _ := lookup(old, key)
+		nv, ok := lookup(values, key)
+		if ok && !reflect.DeepEqual(ov, nv) {
+			changes = append(changes, change{key: key, value: nv, observers: observers})
+		}
+	}
+	c.mu.Unlock()
+	for _, ch := range changes {
+		logger.Infof("[config] key %s changed", ch.key)
+		for _, o := range ch.observers {
+			o(ch.key, ch.value)
+		}
+	}
+	return nil
+}
+
+// mergeKeyValues decodes and merges the contents in order.
+func mergeKeyValues(kvs [][]*KeyValue) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, list := range kvs {
+		for _, kv := range list {
+			m, err := decode(kv)
+			if err != nil {
+				return nil, err
+			}
+			merge(values, m)
+		}
+	}
+	return values, nil
+}
+
+// lookup walks the dot separated key.
+func lookup(values map[string]interface{}, key string) (interface{}, bool) {
+	if key == "" {
+		return values, true
+	}
+	var node interface{} = values
+	for _, k := range strings.Split(key, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// convert decodes value into v through json, the strings of the fields that are not strings
+// are parsed, e.g. the values of the environment, and the numbers and booleans of the string
+// fields are formatted, e.g. a numeric token.
+func convert(value interface{}, v interface{}) error {
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Ptr {
+		value = weaken(value, t.Elem())
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// weaken returns a copy of value with the strings, numbers and booleans converted to the kind
+// of t, the values that do not convert are kept for json to report them.
+func weaken(value interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// the types decoding themselves get the value as is
+	if reflect.PointerTo(t).Implements(jsonUnmarshaler) || reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		switch t.Kind() {
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				return json.Number(v)
+			}
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(v), &parsed); err == nil {
+				return weaken(parsed, t)
+			}
+		}
+	case float64:
+		if t.Kind() == reflect.String {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case bool:
+		if t.Kind() == reflect.String {
+			return strconv.FormatBool(v)
+		}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := fieldTypes(t)
+			for k, e := range v {
+				if ft, ok := fields[strings.ToLower(k)]; ok {
+					e = weaken(e, ft)
+				}
+				m[k] = e
+			}
+		case reflect.Map:
+			for k, e := range v {
+				m[k] = weaken(e, t.Elem())
+			}
+		default:
+			return v
+		}
+		return m
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return v
+		}
+		list := make([]interface{}, len(v))
+		for i, e := range v {
+			list[i] = weaken(e, t.Elem())
+		}
+		return list
+	}
+	return value
+}
+
+// fieldTypes returns the types of the fields of a struct by their lower case json names,
+// the fields of the embedded structs without a name included, the way json matches keys.
+func fieldTypes(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k, et := range fieldTypes(ft) {
+				if _, ok := fields[k]; !ok {
+					fields[k] = et
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
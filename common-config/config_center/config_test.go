@@ -0,0 +1,155 @@
+package config_center
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/format"
+)
+
+// nacosStandIn is a local http stand-in of the nacos config open api.
+type nacosStandIn struct {
+	mu      sync.Mutex
+	content string
+	changed chan struct{}
+}
+
+func newNacosStandIn(content string) (*nacosStandIn, *httptest.Server) {
+	n := &nacosStandIn{content: content, changed: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc(nacosConfigPath, func(w http.ResponseWriter, r *http.Request) {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		_, _ = w.Write([]byte(n.content))
+	})
+	mux.HandleFunc(nacosListenerPath, func(w http.ResponseWriter, r *http.Request) {
+		listening := r.FormValue("Listening-Configs")
+		fields := strings.Split(strings.TrimSuffix(listening, "\x01"), "\x02")
+		n.mu.Lock()
+		modified := len(fields) < 3 || fields[2] != contentMd5([]byte(n.content))
+		changed := n.changed
+		n.mu.Unlock()
+		if !modified {
+			select {
+			case <-changed:
+			case <-time.After(100 * time.Millisecond):
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_, _ = w.Write([]byte(url.QueryEscape(listening)))
+	})
+	return n, httptest.NewServer(mux)
+}
+
+func (n *nacosStandIn) publish(content string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.content = content
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+type testConfig struct {
+	Application struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"application"`
+	AdminConfig struct {
+		Token string   `json:"token"`
+		Port  int      `json:"port"`
+		Hosts []string `json:"hosts"`
+	} `json:"admin_config"`
+	HttpConfig format.HttpConfig `json:"http_config"`
+	LogConfig  format.LogConfig  `json:"log_config"`
+}
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	file := "[application]\nname = \"admin\"\n\n[http_config]\nlisten_http = \":8181\"\n\n[log_config]\nlevel = \"info\"\n"
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_CONFIG_HTTP_CONFIG__PROMETHEUS", "true")
+	nacos, srv := newNacosStandIn(`{"log_config": {"level": "warn"}}`)
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.ParseUint(u.Port(), 10, 64)
+	c := New(WithSource(
+		NewFileSource(path),
+		NewEnvSource("TEST_CONFIG_"),
+		NewNacosSource(format.NacosConfig{Host: u.Hostname(), Port: port, DataId: "user.json"}),
+	))
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer c.Close()
+	var conf testConfig
+	if err := c.Scan(&conf); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if conf.Application.Name != "admin" || conf.HttpConfig.ListenHTTP != ":8181" || !conf.HttpConfig.Prometheus || conf.LogConfig.Level != "warn" {
+		t.Errorf("Scan() = %+v, want file, env and nacos merged", conf)
+	}
+	levels := make(chan string, 1)
+	OnChange(c, "log_config.level", func(level string) {
+		levels <- level
+	})
+	nacos.publish(`{"log_config": {"level": "debug"}}`)
+	select {
+	case level := <-levels:
+		if level != "debug" {
+			t.Errorf("OnChange() level = %s, want debug", level)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnChange() not called")
+	}
+	if level, err := Get[string](c, "log_config.level"); err != nil || level != "debug" {
+		t.Errorf("Get() = %s, %v, want debug", level, err)
+	}
+	if _, err := c.Value("log_config.missing"); err != ErrNotFound {
+		t.Errorf("Value() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestConfig_Scan_Convert(t *testing.T) {
+	t.Setenv("TEST_CONVERT_ADMIN_CONFIG__TOKEN", "123456")
+	t.Setenv("TEST_CONVERT_ADMIN_CONFIG__PORT", "8080")
+	t.Setenv("TEST_CONVERT_ADMIN_CONFIG__HOSTS", `["a","b"]`)
+	t.Setenv("TEST_CONVERT_APPLICATION__VERSION", "1.10")
+	t.Setenv("TEST_CONVERT_HTTP_CONFIG__PROMETHEUS", "true")
+	c := New(WithSource(NewEnvSource("TEST_CONVERT_")))
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer c.Close()
+	var conf testConfig
+	if err := c.Scan(&conf); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	admin := conf.AdminConfig
+	if admin.Token != "123456" || admin.Port != 8080 || len(admin.Hosts) != 2 || conf.Application.Version != "1.10" || !conf.HttpConfig.Prometheus {
+		t.Errorf("Scan() = %+v, want the environment converted to the field types", conf)
+	}
+
+	// a number of a json or toml source sets a string field too
+	var app struct {
+		Version string `json:"version"`
+		Port    int    `json:"port"`
+	}
+	if err := convert(map[string]interface{}{"version": 2.5, "port": "9090"}, &app); err != nil || app.Version != "2.5" || app.Port != 9090 {
+		t.Errorf("convert() = %+v, %v, want version 2.5 and port 9090", app, err)
+	}
+	if token, err := Get[string](c, "admin_config.token"); err != nil || token != "123456" {
+		t.Errorf("Get() = %s, %v, want 123456", token, err)
+	}
+}
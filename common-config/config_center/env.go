@@ -0,0 +1,70 @@
+package config_center
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+)
+
+var _ Source = (*envSource)(nil)
+
+type envSource struct {
+	prefix string
+}
+
+// NewEnvSource create a source of the environment variables starting with prefix.
+// The rest of the name is the lower case key path separated by double underscore,
+// e.g. USER_HTTP_CONFIG__LISTEN_HTTP=:8080 with prefix USER_ is http_config.listen_http.
+// The values are strings, Scan and Get convert them to the types of the fields they set.
+func NewEnvSource(prefix string) Source {
+	return &envSource{prefix: prefix}
+}
+
+func (e *envSource) Load() ([]*KeyValue, error) {
+	m := make(map[string]interface{})
+	for _, env := range os.Environ() {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(name, e.prefix) || name == e.prefix {
+			continue
+		}
+		path := strings.Split(strings.ToLower(strings.TrimPrefix(name, e.prefix)), "__")
+		node := m
+		for _, k := range path[:len(path)-1] {
+			child, ok := node[k].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[k] = child
+			}
+			node = child
+		}
+		node[path[len(path)-1]] = value
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return []*KeyValue{{Key: "env:" + e.prefix, Value: data, Format: FormatJson}}, nil
+}
+
+// Watch returns a watcher never firing, the environment of a process does not change.
+func (e *envSource) Watch() (Watcher, error) {
+	return &envWatcher{stopped: make(chan struct{})}, nil
+}
+
+type envWatcher struct {
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (w *envWatcher) Next() ([]*KeyValue, error) {
+	<-w.stopped
+	return nil, ErrWatcherStopped
+}
+
+func (w *envWatcher) Stop() error {
+	w.once.Do(func() {
+		close(w.stopped)
+	})
+	return nil
+}
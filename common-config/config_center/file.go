@@ -0,0 +1,73 @@
+package config_center
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultFileWatchInterval is the interval a file watcher polls the file.
+const DefaultFileWatchInterval = 3 * time.Second
+
+var _ Source = (*fileSource)(nil)
+
+type fileSource struct {
+	path     string
+	interval time.Duration
+}
+
+// NewFileSource create a source of a toml or json file, the format follows the extension.
+func NewFileSource(path string) Source {
+	return &fileSource{path: path, interval: DefaultFileWatchInterval}
+}
+
+func (f *fileSource) Load() ([]*KeyValue, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return []*KeyValue{{Key: f.path, Value: data, Format: formatOf(f.path)}}, nil
+}
+
+func (f *fileSource) Watch() (Watcher, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileWatcher{f: f, last: data, stopped: make(chan struct{})}, nil
+}
+
+type fileWatcher struct {
+	f       *fileSource
+	last    []byte
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (w *fileWatcher) Next() ([]*KeyValue, error) {
+	ticker := time.NewTicker(w.f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopped:
+			return nil, ErrWatcherStopped
+		case <-ticker.C:
+			data, err := os.ReadFile(w.f.path)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(data, w.last) {
+				w.last = data
+				return []*KeyValue{{Key: w.f.path, Value: data, Format: formatOf(w.f.path)}}, nil
+			}
+		}
+	}
+}
+
+func (w *fileWatcher) Stop() error {
+	w.once.Do(func() {
+		close(w.stopped)
+	})
+	return nil
+}
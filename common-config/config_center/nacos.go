@@ -0,0 +1,194 @@
+package config_center
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/format"
+)
+
+const (
+	// DefaultNacosPollTimeout is the long polling timeout of the nacos listener.
+	DefaultNacosPollTimeout = 30 * time.Second
+	nacosConfigPath         = "/nacos/v1/cs/configs"
+	nacosListenerPath       = "/nacos/v1/cs/configs/listener"
+	nacosDefaultGroup       = "DEFAULT_GROUP"
+)
+
+var _ Source = (*nacosSource)(nil)
+
+// NacosOption is nacos source option.
+type NacosOption func(s *nacosSource)
+
+// WithNacosFormat with the format of the nacos config, default follows the data id extension.
+func WithNacosFormat(f string) NacosOption {
+	return func(s *nacosSource) {
+		s.format = f
+	}
+}
+
+// WithNacosPollTimeout with the long polling timeout of the nacos listener.
+func WithNacosPollTimeout(timeout time.Duration) NacosOption {
+	return func(s *nacosSource) {
+		s.pollTimeout = timeout
+	}
+}
+
+// WithNacosClient with the http client requesting nacos.
+func WithNacosClient(c *http.Client) NacosOption {
+	return func(s *nacosSource) {
+		s.client = c
+	}
+}
+
+type nacosSource struct {
+	conf        format.NacosConfig
+	baseUrl     string
+	format      string
+	pollTimeout time.Duration
+	client      *http.Client
+}
+
+// NewNacosSource create a source of a config in nacos, it talks to the nacos open api
+// and watches the config through the long polling listener.
+func NewNacosSource(conf format.NacosConfig, opts ...NacosOption) Source {
+	if conf.Group == "" {
+		conf.Group = nacosDefaultGroup
+	}
+	s := &nacosSource{
+		conf:        conf,
+		baseUrl:     "http://" + conf.Host + ":" + strconv.FormatUint(conf.Port, 10),
+		format:      formatOf(conf.DataId),
+		pollTimeout: DefaultNacosPollTimeout,
+		client:      http.DefaultClient,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+func (s *nacosSource) Load() ([]*KeyValue, error) {
+	data, err := s.get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return []*KeyValue{s.keyValue(data)}, nil
+}
+
+func (s *nacosSource) Watch() (Watcher, error) {
+	data, err := s.get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &nacosWatcher{s: s, md5: contentMd5(data), ctx: ctx, cancel: cancel}, nil
+}
+
+func (s *nacosSource) keyValue(data []byte) *KeyValue {
+	return &KeyValue{Key: s.conf.Group + "/" + s.conf.DataId, Value: data, Format: s.format}
+}
+
+// get fetches the config content.
+func (s *nacosSource) get(ctx context.Context) ([]byte, error) {
+	q := url.Values{}
+	q.Set("dataId", s.conf.DataId)
+	q.Set("group", s.conf.Group)
+	if s.conf.NameSpace != "" {
+		q.Set("tenant", s.conf.NameSpace)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseUrl+nacosConfigPath+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nacos get config %s/%s status %d: %s", s.conf.Group, s.conf.DataId, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// listen long polls the listener and reports whether the config content changed.
+func (s *nacosSource) listen(ctx context.Context, md5 string) (bool, error) {
+	fields := []string{s.conf.DataId, s.conf.Group, md5}
+	if s.conf.NameSpace != "" {
+		fields = append(fields, s.conf.NameSpace)
+	}
+	form := url.Values{}
+	form.Set("Listening-Configs", strings.Join(fields, "\x02")+"\x01")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseUrl+nacosListenerPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Long-Pulling-Timeout", strconv.FormatInt(s.pollTimeout.Milliseconds(), 10))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("nacos listen config %s/%s status %d: %s", s.conf.Group, s.conf.DataId, resp.StatusCode, body)
+	}
+	return len(strings.TrimSpace(string(body))) > 0, nil
+}
+
+func contentMd5(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type nacosWatcher struct {
+	s      *nacosSource
+	md5    string
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (w *nacosWatcher) Next() ([]*KeyValue, error) {
+	for {
+		changed, err := w.s.listen(w.ctx, w.md5)
+		if w.ctx.Err() != nil {
+			return nil, ErrWatcherStopped
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+		data, err := w.s.get(w.ctx)
+		if err != nil {
+			return nil, err
+		}
+		if sum := contentMd5(data); sum != w.md5 {
+			w.md5 = sum
+			return []*KeyValue{w.s.keyValue(data)}, nil
+		}
+	}
+}
+
+func (w *nacosWatcher) Stop() error {
+	w.cancel()
+	return nil
+}
@@ -0,0 +1,85 @@
+package config_center
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	FormatJson = "json"
+	FormatToml = "toml"
+)
+
+// KeyValue is a config content loaded from a source.
+type KeyValue struct {
+	Key    string
+	Value  []byte
+	Format string
+}
+
+// Source is config source.
+type Source interface {
+	// Load returns the contents of the source.
+	Load() ([]*KeyValue, error)
+	// Watch returns a watcher of the source changes.
+	Watch() (Watcher, error)
+}
+
+// Watcher watches a source.
+type Watcher interface {
+	// Next blocks until the source changed and returns the new contents,
+	// it returns ErrWatcherStopped once the watcher is stopped.
+	Next() ([]*KeyValue, error)
+	// Stop close the watcher.
+	Stop() error
+}
+
+// formatOf returns the format of a config file according to its extension, default json.
+func formatOf(path string) string {
+	if strings.EqualFold(filepath.Ext(path), "."+FormatToml) {
+		return FormatToml
+	}
+	return FormatJson
+}
+
+// decode unmarshal the key value into a map.
+func decode(kv *KeyValue) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if len(kv.Value) == 0 {
+		return m, nil
+	}
+	var err error
+	switch kv.Format {
+	case FormatToml:
+		err = toml.Unmarshal(kv.Value, &m)
+	case FormatJson, "":
+		err = json.Unmarshal(kv.Value, &m)
+	default:
+		return nil, fmt.Errorf("config %s unsupported format %s", kv.Key, kv.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s decode catch err=%v", kv.Key, err)
+	}
+	return m, nil
+}
+
+// merge deep merges src into dst, values of src win.
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sv, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dv, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dv = make(map[string]interface{})
+			dst[k] = dv
+		}
+		merge(dv, sv)
+	}
+}
@@ -7,4 +7,12 @@ const (
 	CodeInvalidParams        = 10001
 	CodeInvalidParamsI18N    = "Common.InvalidParams"
 	CodeInvalidParamsMessage = "invalid params"
+
+	CodeTooManyRequests        = 10002
+	CodeTooManyRequestsI18N    = "Common.TooManyRequests"
+	CodeTooManyRequestsMessage = "too many requests"
+
+	CodeBreakerOpen        = 10003
+	CodeBreakerOpenI18N    = "Common.BreakerOpen"
+	CodeBreakerOpenMessage = "circuit breaker is open"
 )
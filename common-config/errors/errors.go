@@ -0,0 +1,269 @@
+// Package errors is the error model shared by the gRPC and the HTTP services.
+//
+// An Error carries a numeric code of common_errors, an i18n reason key and metadata.
+// It converts to a gRPC status with an errdetails.ErrorInfo, which FromError and the
+// gRPC client interceptors decode back into the same Error, and to a FailDto by
+// common.ResponseFail on the HTTP side.
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	commonErrors "github.com/weiqiangxu/micro_project/common-config/error_code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+const (
+	// Domain is the domain of the errdetails.ErrorInfo of the errors.
+	Domain = "micro_project"
+
+	// CodeKey is the ErrorInfo metadata key of the numeric code.
+	CodeKey = "code"
+
+	// UnknownCode is the code of the errors not carrying one.
+	UnknownCode = 10000
+	// UnknownReason is the reason of the errors not carrying one.
+	UnknownReason = "Common.Unknown"
+)
+
+// ErrInvalidParams is the error of invalid request parameters, compared by errors.Is.
+var ErrInvalidParams = New(commonErrors.CodeInvalidParams, commonErrors.CodeInvalidParamsI18N, commonErrors.CodeInvalidParamsMessage)
+
+// ErrTooManyRequests is the error of the requests rejected by a rate or concurrency limit.
+var ErrTooManyRequests = New(commonErrors.CodeTooManyRequests, commonErrors.CodeTooManyRequestsI18N, commonErrors.CodeTooManyRequestsMessage)
+
+// ErrBreakerOpen is the error of the calls rejected by an open circuit breaker of a client.
+var ErrBreakerOpen = New(commonErrors.CodeBreakerOpen, commonErrors.CodeBreakerOpenI18N, commonErrors.CodeBreakerOpenMessage)
+
+// Error is an error with a numeric code, an i18n reason key and metadata.
+type Error struct {
+	// Code is the numeric code, e.g. common_errors.CodeInvalidParams.
+	Code int
+	// Reason is the i18n key, e.g. common_errors.CodeInvalidParamsI18N.
+	Reason string
+	// Message is the description for the developers.
+	Message string
+	// Metadata is the extra information of the error, e.g. the invalid field.
+	Metadata map[string]string
+
+	details []proto.Message
+	cause   error
+}
+
+// New returns an error with code, reason and message.
+func New(code int, reason, message string) *Error {
+	return &Error{Code: code, Reason: reason, Message: message}
+}
+
+// Newf returns an error with code, reason and a formatted message.
+func Newf(code int, reason, format string, a ...interface{}) *Error {
+	return New(code, reason, fmt.Sprintf(format, a...))
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("error: code = %d reason = %s message = %s metadata = %v cause = %v", e.Code, e.Reason, e.Message, e.Metadata, e.cause)
+	}
+	return fmt.Sprintf("error: code = %d reason = %s message = %s metadata = %v", e.Code, e.Reason, e.Message, e.Metadata)
+}
+
+// Unwrap returns the cause of e.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an Error with the same code and reason.
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if errors.As(target, &t) {
+		return t.Code == e.Code && t.Reason == e.Reason
+	}
+	return false
+}
+
+// WithCause returns a copy of e caused by err, the cause is not sent to the clients.
+func (e *Error) WithCause(err error) *Error {
+	clone := e.clone()
+	clone.cause = err
+	return clone
+}
+
+// WithMetadata returns a copy of e with md added to its metadata.
+func (e *Error) WithMetadata(md map[string]string) *Error {
+	clone := e.clone()
+	for k, v := range md {
+		clone.Metadata[k] = v
+	}
+	return clone
+}
+
+// WithDetails returns a copy of e with details added to the details of its gRPC status,
+// e.g. an errdetails.BadRequest with the field violations.
+func (e *Error) WithDetails(details ...proto.Message) *Error {
+	clone := e.clone()
+	clone.details = append(clone.details[:len(clone.details):len(clone.details)], details...)
+	return clone
+}
+
+// Details returns the details of the gRPC status other than the ErrorInfo.
+func (e *Error) Details() []proto.Message {
+	return e.details
+}
+
+// WithMessage returns a copy of e with a formatted message.
+func (e *Error) WithMessage(format string, a ...interface{}) *Error {
+	clone := e.clone()
+	clone.Message = fmt.Sprintf(format, a...)
+	return clone
+}
+
+func (e *Error) clone() *Error {
+	clone := *e
+	clone.Metadata = make(map[string]string, len(e.Metadata))
+	for k, v := range e.Metadata {
+		clone.Metadata[k] = v
+	}
+	return &clone
+}
+
+// GRPCStatus converts e to a status with an errdetails.ErrorInfo, the gRPC server calls it
+// for the errors returned by the handlers.
+func (e *Error) GRPCStatus() *status.Status {
+	md := make(map[string]string, len(e.Metadata)+1)
+	for k, v := range e.Metadata {
+		md[k] = v
+	}
+	md[CodeKey] = strconv.Itoa(e.Code)
+	s := status.New(GRPCCode(e.Code), e.Message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: e.Reason, Domain: Domain, Metadata: md}}
+	for _, detail := range e.details {
+		details = append(details, protoadapt.MessageV1Of(detail))
+	}
+	if d, err := s.WithDetails(details...); err == nil {
+		return d
+	}
+	return s
+}
+
+// GRPCError returns the status error of the Error in the chain of err, so the message sent
+// to the client is the one of the Error and not the one of the wrapping errors.
+func GRPCError(err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.GRPCStatus().Err()
+	}
+	return err
+}
+
+// FromError returns the Error of err: the Error in its chain, the one decoded from the
+// ErrorInfo of its gRPC status, or an UnknownCode error wrapping err. It returns nil for nil.
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if e, ok := fromStatus(err); ok {
+		return e
+	}
+	return New(UnknownCode, UnknownReason, err.Error()).WithCause(err)
+}
+
+// fromStatus decodes the Error of the ErrorInfo of a gRPC status error.
+func fromStatus(err error) (*Error, bool) {
+	s, ok := status.FromError(err)
+	if !ok {
+		return nil, false
+	}
+	var (
+		e       *Error
+		details []proto.Message
+	)
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == Domain && e == nil {
+			code, err := strconv.Atoi(info.Metadata[CodeKey])
+			if err != nil {
+				return nil, false
+			}
+			e = New(code, info.Reason, s.Message())
+			e.Metadata = make(map[string]string, len(info.Metadata))
+			for k, v := range info.Metadata {
+				if k != CodeKey {
+					e.Metadata[k] = v
+				}
+			}
+			continue
+		}
+		if m, ok := detail.(protoadapt.MessageV1); ok {
+			details = append(details, protoadapt.MessageV2Of(m))
+		}
+	}
+	if e == nil {
+		return nil, false
+	}
+	e.details = details
+	return e, true
+}
+
+// Decode returns the Error decoded from the status of a gRPC error, or err itself when the
+// status carries no ErrorInfo, e.g. a deadline exceeded.
+func Decode(err error) error {
+	if e, ok := fromStatus(err); ok {
+		return e
+	}
+	return err
+}
+
+// Code returns the code of err, CodeSuccess for nil.
+func Code(err error) int {
+	if err == nil {
+		return commonErrors.CodeSuccess
+	}
+	return FromError(err).Code
+}
+
+// Reason returns the reason of err, an empty string for nil.
+func Reason(err error) string {
+	if err == nil {
+		return ""
+	}
+	return FromError(err).Reason
+}
+
+var (
+	grpcCodesMu sync.RWMutex
+	grpcCodes   = map[int]codes.Code{
+		UnknownCode:                            codes.Unknown,
+		commonErrors.CodeInvalidParams:         codes.InvalidArgument,
+		commonErrors.CodeTooManyRequests:       codes.ResourceExhausted,
+		commonErrors.CodeBreakerOpen:           codes.Unavailable,
+		commonErrors.SmsSendCodeUpperLimitCode: codes.ResourceExhausted,
+	}
+)
+
+// RegisterGRPCCode maps a numeric code to the code of the gRPC status, the codes not
+// registered are codes.Unknown.
+func RegisterGRPCCode(code int, grpcCode codes.Code) {
+	grpcCodesMu.Lock()
+	defer grpcCodesMu.Unlock()
+	grpcCodes[code] = grpcCode
+}
+
+// GRPCCode returns the code of the gRPC status of a numeric code.
+func GRPCCode(code int) codes.Code {
+	grpcCodesMu.RLock()
+	defer grpcCodesMu.RUnlock()
+	if c, ok := grpcCodes[code]; ok {
+		return c
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	commonErrors "github.com/weiqiangxu/micro_project/common-config/error_code"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestError_GRPCStatus(t *testing.T) {
+	err := ErrInvalidParams.WithMessage("name is required").WithMetadata(map[string]string{"field": "name"})
+	s, ok := status.FromError(GRPCError(fmt.Errorf("wrapped: %w", err)))
+	if !ok {
+		t.Fatalf("GRPCError() is not a status")
+	}
+	if s.Code() != codes.InvalidArgument || s.Message() != "name is required" {
+		t.Errorf("status = %v %q, want %v %q", s.Code(), s.Message(), codes.InvalidArgument, "name is required")
+	}
+	decoded := Decode(s.Err())
+	want := &Error{
+		Code:     commonErrors.CodeInvalidParams,
+		Reason:   commonErrors.CodeInvalidParamsI18N,
+		Message:  "name is required",
+		Metadata: map[string]string{"field": "name"},
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("Decode() = %#v, want %#v", decoded, want)
+	}
+	if !errors.Is(decoded, ErrInvalidParams) {
+		t.Errorf("errors.Is(%v, ErrInvalidParams) = false", decoded)
+	}
+	if status.Code(decoded) != codes.InvalidArgument {
+		t.Errorf("status.Code() = %v, want %v", status.Code(decoded), codes.InvalidArgument)
+	}
+}
+
+func TestFromError(t *testing.T) {
+	cause := io.ErrUnexpectedEOF
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantReason string
+	}{
+		{"nil", nil, commonErrors.CodeSuccess, ""},
+		{"error", New(commonErrors.SmsSendCodeUpperLimitCode, commonErrors.SmsSendCodeUpperLimit, "limit"), commonErrors.SmsSendCodeUpperLimitCode, commonErrors.SmsSendCodeUpperLimit},
+		{"wrapped", fmt.Errorf("send: %w", ErrInvalidParams.WithCause(cause)), commonErrors.CodeInvalidParams, commonErrors.CodeInvalidParamsI18N},
+		{"status without info", status.Error(codes.DeadlineExceeded, "deadline"), UnknownCode, UnknownReason},
+		{"other error", cause, UnknownCode, UnknownReason},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.err); got != tt.wantCode {
+				t.Errorf("Code() = %d, want %d", got, tt.wantCode)
+			}
+			if got := Reason(tt.err); got != tt.wantReason {
+				t.Errorf("Reason() = %s, want %s", got, tt.wantReason)
+			}
+		})
+	}
+	if err := ErrInvalidParams.WithCause(cause); !errors.Is(err, cause) {
+		t.Errorf("errors.Is(%v, %v) = false", err, cause)
+	}
+	if err := status.Error(codes.DeadlineExceeded, "deadline"); Decode(err) != err {
+		t.Errorf("Decode() = %v, want the status error", Decode(err))
+	}
+}
+
+func TestRegisterGRPCCode(t *testing.T) {
+	RegisterGRPCCode(40401, codes.NotFound)
+	if got := status.Code(New(40401, "User.NotFound", "user not found")); got != codes.NotFound {
+		t.Errorf("status.Code() = %v, want %v", got, codes.NotFound)
+	}
+	if got := GRPCCode(40402); got != codes.Unknown {
+		t.Errorf("GRPCCode() = %v, want %v", got, codes.Unknown)
+	}
+}
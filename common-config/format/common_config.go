@@ -17,15 +17,47 @@ type HttpConfig struct {
 }
 
 type GrpcConfig struct {
-	Addr string `toml:"addr" json:"addr" validate:"hostname_port" long:"addr" description:"grpc server addr,format is host:port"`
+	Addr string `toml:"addr" json:"addr" validate:"hostname_port|uri" long:"addr" description:"grpc server addr,format is host:port or discovery:///<service name>"`
+	// CertFile && KeyFile 服务端证书或mTLS的客户端证书, 文件修改后自动重新加载
+	CertFile string `toml:"cert_file" json:"cert_file" long:"cert_file" description:"tls certificate file"`
+	KeyFile  string `toml:"key_file" json:"key_file" long:"key_file" description:"tls private key file"`
+	// CAFile 服务端用于校验客户端证书(mTLS), 客户端用于校验服务端证书
+	CAFile     string `toml:"ca_file" json:"ca_file" long:"ca_file" description:"tls CA bundle file"`
+	ServerName string `toml:"server_name" json:"server_name" long:"server_name" description:"server name verified by the client"`
+	// PoolMaxCap 客户端使用GRPC连接池时的最大连接数, 0则不使用连接池
+	PoolInitialCap int `toml:"pool_initial_cap" json:"pool_initial_cap" long:"pool_initial_cap" description:"minimum connections of the client pool"`
+	PoolMaxCap     int `toml:"pool_max_cap" json:"pool_max_cap" long:"pool_max_cap" description:"maximum connections of the client pool, 0 disables the pool"`
+	PoolMaxIdle    int `toml:"pool_max_idle" json:"pool_max_idle" long:"pool_max_idle" description:"maximum idle connections of the client pool"`
+	// PoolMode lease每次调用独占连接, least_streams和p2c多个调用共享进行中的调用最少的连接
+	PoolMode string `toml:"pool_mode" json:"pool_mode" long:"pool_mode" description:"connection selection of the client pool: lease, least_streams or p2c"`
+}
+
+type RegistryConfig struct {
+	File string `toml:"file" json:"file" long:"file" description:"file path of the local service registry"`
+}
+
+type AdminConfig struct {
+	Token string `toml:"token" json:"token" long:"token" description:"token of the admin endpoints, leave it empty to disable them"`
 }
 
 type LogConfig struct {
 	Debug  bool   `toml:"debug" json:"debug" long:"debug" description:"enable debug to disable stacktrace"`
-	Level  string `toml:"level" json:"level" long:"level" description:"log level, can be empty, or one of debug|info|warn|error|fatal|panic"`
+	Level  string `toml:"level" json:"level" validate:"omitempty,oneof=debug info warn error fatal panic" long:"level" description:"log level, can be empty, or one of debug|info|warn|error|fatal|panic"`
 	Output string `toml:"output" json:"output" long:"output" description:"set output file path, can be filepath or stdout|stderr"`
 	// Encoding sets the logger's encoding. Valid values are "json" and "console". default: json
 	Encoding string `toml:"encoding" json:"encoding" long:"encoding" description:"set encoding, the default is json, if not empty, must be one of: console|json"`
+	// ErrorOutput receives a copy of the error level logs, can be filepath or stdout|stderr
+	ErrorOutput string `toml:"error_output" json:"error_output" long:"error_output" description:"set error level output file path, can be filepath or stdout|stderr"`
+	// MaxSize rotates the output files when they reach max_size megabytes, 0 means no limit
+	MaxSize int `toml:"max_size" json:"max_size" validate:"gte=0" long:"max_size" description:"rotate output files at max_size megabytes"`
+	// RotateInterval rotates the output files hourly or daily
+	RotateInterval string `toml:"rotate_interval" json:"rotate_interval" validate:"omitempty,oneof=hourly daily" long:"rotate_interval" description:"rotate output files, can be empty, or one of hourly|daily"`
+	MaxBackups     int    `toml:"max_backups" json:"max_backups" validate:"gte=0" long:"max_backups" description:"max number of rotated files to retain, 0 retains all"`
+	MaxAge         int    `toml:"max_age" json:"max_age" validate:"gte=0" long:"max_age" description:"max days to retain rotated files, 0 retains them forever"`
+	Compress       bool   `toml:"compress" json:"compress" long:"compress" description:"gzip rotated files"`
+	// AsyncBufferSize buffers the logs and writes them in background, 0 writes them synchronously
+	AsyncBufferSize int  `toml:"async_buffer_size" json:"async_buffer_size" validate:"gte=0" long:"async_buffer_size" description:"number of buffered logs written in background, 0 writes synchronously"`
+	AsyncBlock      bool `toml:"async_block" json:"async_block" long:"async_block" description:"block when the async buffer is full instead of dropping logs"`
 }
 
 type MysqlConfig struct {
@@ -5,8 +5,10 @@ import (
 	"net/http"
 
 	common_errors "github.com/weiqiangxu/micro_project/common-config/error_code"
+	xerrors "github.com/weiqiangxu/micro_project/common-config/errors"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 var DefaultValidator = validator.New()
@@ -25,8 +27,9 @@ type FailDto struct {
 
 // ErrorDto 失败数据-错误 结构体
 type ErrorDto struct {
-	Code    string `json:"code"`    // 错误码
-	Message string `json:"message"` // 错误描述
+	Code     string            `json:"code"`               // 错误码
+	Message  string            `json:"message"`            // 错误描述
+	Metadata map[string]string `json:"metadata,omitempty"` // 错误附加信息
 }
 
 // Page 统一页码结构
@@ -78,6 +81,17 @@ func ResponseError(c *gin.Context, code int, codeStr string, err error) {
 	c.JSON(http.StatusOK, FailDto{Code: code, Error: ErrorDto{Code: codeStr, Message: err.Error()}})
 }
 
+// NewFailDto 将错误转换为失败数据, gRPC客户端返回的错误解码为原始的错误码
+func NewFailDto(err error) FailDto {
+	e := xerrors.FromError(err)
+	return FailDto{Code: e.Code, Error: ErrorDto{Code: e.Reason, Message: e.Message, Metadata: e.Metadata}}
+}
+
+// ResponseFail 返回错误, 错误码和i18n key取自 errors.Error
+func ResponseFail(c *gin.Context, err error) {
+	c.JSON(http.StatusOK, NewFailDto(err))
+}
+
 // ResponseEncryptSuccess 加密返回
 func ResponseEncryptSuccess(c *gin.Context, data interface{}) {
 }
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	common_errors "github.com/weiqiangxu/micro_project/common-config/error_code"
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+)
+
+func TestNewFailDto(t *testing.T) {
+	err := errors.ErrInvalidParams.WithMessage("phone is required").WithMetadata(map[string]string{"field": "phone"})
+	want := FailDto{
+		Code: common_errors.CodeInvalidParams,
+		Error: ErrorDto{
+			Code:     common_errors.CodeInvalidParamsI18N,
+			Message:  "phone is required",
+			Metadata: map[string]string{"field": "phone"},
+		},
+	}
+	// the error decoded by the gRPC client is the same
+	for _, err := range []error{err, errors.Decode(err.GRPCStatus().Err())} {
+		if got := NewFailDto(err); !reflect.DeepEqual(got, want) {
+			t.Errorf("NewFailDto() = %+v, want %+v", got, want)
+		}
+	}
+}
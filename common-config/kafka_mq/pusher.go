@@ -9,6 +9,9 @@ import (
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
+// log 组件日志, 可通过 logger.SetLevelFor("kafka_mq", ...) 单独调整级别
+var log = logger.Named("kafka_mq")
+
 type PusherConfig struct {
 	Addr      []string
 	Mechanism string
@@ -50,7 +53,7 @@ func NewPusher(config *PusherConfig) (Pusher, error) {
 	}
 	producer, err := kafka.NewProducer(&c)
 	if err != nil {
-		logger.Fatal(err)
+		log.Fatal(err)
 		return nil, err
 	}
 	p := &pusher{}
@@ -68,7 +71,7 @@ func (p *pusher) SendMessage(topic string, message []byte) error {
 		Value:          message,
 	}
 	if err := p.producer.Produce(msg, nil); err != nil {
-		logger.Errorf("kafka_mq send message err:", err)
+		log.Errorf("kafka_mq send message err:", err)
 	}
 	return nil
 }
@@ -80,7 +83,7 @@ func (p *pusher) SendMessageWithKey(topic string, key string, message []byte) er
 		Value:          message,
 	}
 	if err := p.producer.Produce(msg, nil); err != nil {
-		logger.Errorf("kafka_mq send message err:", err)
+		log.Errorf("kafka_mq send message err:", err)
 	}
 	return nil
 }
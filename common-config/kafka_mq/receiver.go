@@ -6,8 +6,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/weiqiangxu/micro_project/common-config/logger"
-
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
@@ -99,13 +97,13 @@ type receiver struct {
 
 func (r *receiver) Start(ctx context.Context) error {
 	if r.receiveChan == nil {
-		logger.Fatal("can't use nil chan to receive kafka_mq msg")
+		log.Fatal("can't use nil chan to receive kafka_mq msg")
 	}
-	logger.Info("kafka receiver start!")
+	log.Info("kafka receiver start!")
 	for {
 		select {
 		case <-ctx.Done():
-			logger.Infof("kafka_mq receiver stopping")
+			log.Infof("kafka_mq receiver stopping")
 			return nil
 		default:
 			r.consume()
@@ -125,7 +123,7 @@ func (r *receiver) Stop(ctx context.Context) error {
 func (r *receiver) consume() {
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Errorf("consumer recover err=%v", r)
+			log.Errorf("consumer recover err=%v", r)
 		}
 	}()
 	msg, err := r.consumer.ReadMessage(100 * time.Millisecond)
@@ -134,7 +132,7 @@ func (r *receiver) consume() {
 		return
 	}
 	if r.isStdOut {
-		logger.Infof("handlePush partition =%d data = %s", msg.TopicPartition.Partition, msg.Value)
+		log.Infof("handlePush partition =%d data = %s", msg.TopicPartition.Partition, msg.Value)
 	}
 	r.receiveChan <- msg
 }
@@ -149,7 +147,7 @@ func (r *receiver) SetReceiveChan(ch chan *kafka.Message) error {
 func (r *receiver) Commit(msg *kafka.Message) error {
 	var err error
 	if _, err = r.consumer.CommitMessage(msg); err != nil {
-		logger.Errorf("CommitMessage partition =%d data = %s err=%v,", msg.TopicPartition.Partition, msg.Value, err)
+		log.Errorf("CommitMessage partition =%d data = %s err=%v,", msg.TopicPartition.Partition, msg.Value, err)
 	}
 	return nil
 }
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultAsyncBufferSize is the number of entries buffered by an async output.
+const DefaultAsyncBufferSize = 8192
+
+// AsyncConfig writes the encoded entries to the outputs in a background goroutine.
+type AsyncConfig struct {
+	// BufferSize is the number of buffered entries, DefaultAsyncBufferSize if not set.
+	BufferSize int `json:"bufferSize" yaml:"bufferSize"`
+
+	// Block makes the logging goroutine wait when the buffer is full, by default the
+	// entry is dropped and counted by AsyncDroppedCounter.
+	Block bool `json:"block" yaml:"block"`
+}
+
+// AsyncDroppedCounter counts the entries dropped by the async outputs with a full buffer.
+var AsyncDroppedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "logger_async_dropped_total",
+	Help: "日志异步缓冲区满时丢弃的日志条数",
+})
+
+// asyncWriter buffers the writes to a WriteSyncer, Sync writes the buffered entries
+// before syncing so the entries logged before Sync are never lost.
+type asyncWriter struct {
+	ws      zapcore.WriteSyncer
+	block   bool
+	entries chan []byte
+	flushes chan chan error
+	dropped atomic.Uint64
+
+	// mu is held for reading by the writes buffering an entry, Close takes it for
+	// writing so the goroutine stops only after the entries buffered before Close.
+	mu       sync.RWMutex
+	stopOnce sync.Once
+	stop     chan struct{}
+	quit     chan struct{}
+	stopped  chan struct{}
+}
+
+func newAsyncWriter(ws zapcore.WriteSyncer, config *AsyncConfig) *asyncWriter {
+	size := config.BufferSize
+	if size <= 0 {
+		size = DefaultAsyncBufferSize
+	}
+	w := &asyncWriter{
+		ws:      ws,
+		block:   config.Block,
+		entries: make(chan []byte, size),
+		flushes: make(chan chan error),
+		stop:    make(chan struct{}),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write buffers a copy of p, the encoder reuses p after Write returns.
+// After Close the writes go to the output directly.
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	select {
+	case <-w.stop:
+		return w.ws.Write(p)
+	default:
+	}
+	b := make([]byte, len(p))
+	copy(b, p)
+	if w.block {
+		select {
+		case w.entries <- b:
+		case <-w.stop:
+			return w.ws.Write(p)
+		}
+		return len(p), nil
+	}
+	select {
+	case w.entries <- b:
+	default:
+		w.dropped.Add(1)
+		AsyncDroppedCounter.Inc()
+	}
+	return len(p), nil
+}
+
+// Sync waits until the entries buffered before it are written, then syncs the output.
+func (w *asyncWriter) Sync() error {
+	done := make(chan error)
+	select {
+	case w.flushes <- done:
+		return <-done
+	case <-w.stopped:
+		return w.ws.Sync()
+	}
+}
+
+// Close writes the buffered entries, syncs the output and stops the writing goroutine.
+func (w *asyncWriter) Close() error {
+	w.stopOnce.Do(func() {
+		// the blocked writes stop waiting for the buffer and write to the output
+		close(w.stop)
+		w.mu.Lock()
+		close(w.quit)
+		w.mu.Unlock()
+	})
+	<-w.stopped
+	return nil
+}
+
+// Dropped returns the number of entries dropped by w.
+func (w *asyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+func (w *asyncWriter) run() {
+	for {
+		select {
+		case b := <-w.entries:
+			_, _ = w.ws.Write(b)
+		case done := <-w.flushes:
+			w.drain()
+			done <- w.ws.Sync()
+		case <-w.quit:
+			w.drain()
+			_ = w.ws.Sync()
+			close(w.stopped)
+			return
+		}
+	}
+}
+
+// drain writes the buffered entries.
+func (w *asyncWriter) drain() {
+	for {
+		select {
+		case b := <-w.entries:
+			_, _ = w.ws.Write(b)
+		default:
+			return
+		}
+	}
+}
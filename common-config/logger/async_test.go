@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter blocks the writes until release is closed.
+type slowWriter struct {
+	mu      sync.Mutex
+	release chan struct{}
+	lines   int
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines++
+	return len(p), nil
+}
+
+func (w *slowWriter) Sync() error {
+	return nil
+}
+
+func (w *slowWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines
+}
+
+func TestAsyncWriter_Drop(t *testing.T) {
+	ws := &slowWriter{release: make(chan struct{})}
+	w := newAsyncWriter(ws, &AsyncConfig{BufferSize: 2})
+	// one entry is taken by the writing goroutine, two are buffered, the rest dropped
+	for i := 0; i < 10; i++ {
+		_, _ = w.Write([]byte("line\n"))
+		time.Sleep(time.Millisecond)
+	}
+	close(ws.release)
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if got := ws.count(); got != 3 || w.Dropped() != 7 {
+		t.Errorf("wrote %d and dropped %d, want 3 and 7", got, w.Dropped())
+	}
+}
+
+func TestAsyncWriter_Block(t *testing.T) {
+	ws := &slowWriter{release: make(chan struct{})}
+	w := newAsyncWriter(ws, &AsyncConfig{BufferSize: 1, Block: true})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			_, _ = w.Write([]byte("line\n"))
+		}
+	}()
+	select {
+	case <-done:
+		t.Fatalf("Write() did not block on a full buffer")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(ws.release)
+	<-done
+	_ = w.Sync()
+	if got := ws.count(); got != 5 || w.Dropped() != 0 {
+		t.Errorf("wrote %d and dropped %d, want 5 and 0", got, w.Dropped())
+	}
+}
+
+func TestAsyncWriter_Close(t *testing.T) {
+	ws := &slowWriter{release: make(chan struct{})}
+	w := newAsyncWriter(ws, &AsyncConfig{BufferSize: 4})
+	for i := 0; i < 3; i++ {
+		_, _ = w.Write([]byte("line\n"))
+	}
+	close(ws.release)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := ws.count(); got != 3 {
+		t.Errorf("Close() wrote %d, want the 3 buffered entries", got)
+	}
+	select {
+	case <-w.stopped:
+	default:
+		t.Errorf("Close() did not stop the writing goroutine")
+	}
+	// the writes after Close are not buffered and Sync does not wait for the goroutine
+	_, _ = w.Write([]byte("line\n"))
+	if err := w.Sync(); err != nil || ws.count() != 4 {
+		t.Errorf("after Close wrote %d, Sync() error = %v, want 4 and nil", ws.count(), err)
+	}
+	_ = w.Close()
+}
+
+func TestAsyncWriter_CloseWrites(t *testing.T) {
+	for _, block := range []bool{false, true} {
+		ws := &slowWriter{release: make(chan struct{})}
+		close(ws.release)
+		w := newAsyncWriter(ws, &AsyncConfig{BufferSize: 100000, Block: block})
+		wg := sync.WaitGroup{}
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 2000; j++ {
+					_, _ = w.Write([]byte("line\n"))
+				}
+			}()
+		}
+		// the writes racing Close are either buffered before the goroutine stops or written directly
+		time.Sleep(time.Millisecond)
+		_ = w.Close()
+		wg.Wait()
+		if got := ws.count() + int(w.Dropped()); got != 32000 {
+			t.Errorf("block=%v wrote and dropped %d, want 32000", block, got)
+		}
+	}
+}
+
+func TestSetConfig_ClosesAsync(t *testing.T) {
+	defer SetConfig(NewDefaultConfig())
+	config, path := newFileConfig(t, "replaced.log")
+	config.Async = &AsyncConfig{}
+	SetConfig(config)
+	old := l
+	Info("before replace")
+	SetConfig(NewDefaultConfig())
+	for _, w := range old.async {
+		select {
+		case <-w.stopped:
+		default:
+			t.Errorf("SetConfig() did not close the async output of the replaced logger")
+		}
+	}
+	if lines := readLines(t, path); len(lines) != 1 {
+		t.Errorf("replaced.log = %v, want the entry buffered before SetConfig", lines)
+	}
+}
+
+func TestNewLogger_Async(t *testing.T) {
+	config, path := newFileConfig(t, "async.log")
+	config.Async = &AsyncConfig{}
+	config.InitialFields = map[string]interface{}{"service": "user"}
+	log, _ := newLogger(config)
+	for i := 0; i < 100; i++ {
+		log.Infow("async", "i", i)
+	}
+	if err := log.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	lines := readLines(t, path)
+	if len(lines) != 100 || countLines(lines, `"service":"user"`) != 100 {
+		t.Errorf("logged %d lines, want 100 with the initial fields", len(lines))
+	}
+}
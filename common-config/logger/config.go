@@ -1,6 +1,11 @@
 package logger
 
-import "go.uber.org/zap"
+import (
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/format"
+	"go.uber.org/zap"
+)
 
 type FieldPair []string
 
@@ -33,6 +38,36 @@ type Config struct {
 	// InitialFields is a collection of fields to add to the root logger.
 	InitialFields map[string]interface{} `json:"initialFields" yaml:"initialFields"`
 
+	// Rotate rotates the file paths of OutputPaths and ErrorLevelOutputPaths,
+	// nil keeps the files growing forever.
+	Rotate *RotateConfig `json:"rotate" yaml:"rotate"`
+
+	// ErrorLevelOutputPaths is a list of URLs or file paths receiving a copy of
+	// the entries at ErrorLevel and above.
+	ErrorLevelOutputPaths []string `json:"errorLevelOutputPaths" yaml:"errorLevelOutputPaths"`
+
+	// NamedLevels overrides the level of the named loggers, e.g. {"kafka_mq": DebugLevel}.
+	NamedLevels map[string]Level `json:"namedLevels" yaml:"namedLevels"`
+
+	// Sampling limits the entries per level, nil uses DefaultSampling and an empty map
+	// disables the sampling.
+	Sampling map[Level]*SamplingPolicy `json:"sampling" yaml:"sampling"`
+
+	// MessageSampling limits the entries whose message starts with the key, it takes
+	// precedence over Sampling, e.g. {"kafka_mq send message err": {Rate: 1, Burst: 10}}.
+	MessageSampling map[string]*SamplingPolicy `json:"messageSampling" yaml:"messageSampling"`
+
+	// SuppressedInterval is the minimum interval between the entries reporting the number
+	// of sampled and rate limited entries, DefaultSuppressedInterval if not set.
+	SuppressedInterval time.Duration `json:"suppressedInterval" yaml:"suppressedInterval"`
+
+	// Redact removes the sensitive values from the messages and fields, nil keeps them.
+	Redact *RedactConfig `json:"redact" yaml:"redact"`
+
+	// Async buffers the writes to the outputs, nil writes them synchronously.
+	// Sync writes the buffered entries, it is called when net.App stops.
+	Async *AsyncConfig `json:"async" yaml:"async"`
+
 	EnableColor bool
 	ShortTime   bool
 
@@ -49,6 +84,7 @@ func NewProductionConfig(fields ...FieldPair) *Config {
 		CallerSkip:        2,
 		DisableStacktrace: false,
 		InitialFields:     genInitialFields(fields),
+		Redact:            DefaultRedactConfig(),
 	}
 }
 
@@ -63,6 +99,7 @@ func NewDevelopmentConfig(fields ...FieldPair) *Config {
 		CallerSkip:        2,
 		DisableStacktrace: true,
 		InitialFields:     genInitialFields(fields),
+		Redact:            DefaultRedactConfig(),
 	}
 }
 
@@ -70,6 +107,48 @@ func NewDefaultConfig(fields ...FieldPair) *Config {
 	return NewProductionConfig(fields...)
 }
 
+// NewConfigFromLogConfig builds the config of format.LogConfig, debug uses the development config.
+func NewConfigFromLogConfig(c format.LogConfig, fields ...FieldPair) (*Config, error) {
+	config := NewProductionConfig(fields...)
+	if c.Debug {
+		config = NewDevelopmentConfig(fields...)
+	}
+	if c.Level != "" {
+		level, err := ParseLevel(c.Level)
+		if err != nil {
+			return nil, err
+		}
+		config.Level = level
+	}
+	if c.Encoding != "" {
+		config.Encoding = c.Encoding
+	}
+	if c.Output != "" {
+		config.OutputPaths = []string{c.Output}
+	}
+	if c.ErrorOutput != "" {
+		config.ErrorLevelOutputPaths = []string{c.ErrorOutput}
+	}
+	if c.AsyncBufferSize > 0 {
+		config.Async = &AsyncConfig{BufferSize: c.AsyncBufferSize, Block: c.AsyncBlock}
+	}
+	if c.MaxSize > 0 || c.RotateInterval != "" || c.MaxBackups > 0 || c.MaxAge > 0 || c.Compress {
+		config.Rotate = &RotateConfig{
+			MaxSize:    c.MaxSize,
+			MaxBackups: c.MaxBackups,
+			MaxAge:     time.Duration(c.MaxAge) * 24 * time.Hour,
+			Compress:   c.Compress,
+		}
+		switch c.RotateInterval {
+		case "hourly":
+			config.Rotate.Interval = time.Hour
+		case "daily":
+			config.Rotate.Interval = 24 * time.Hour
+		}
+	}
+	return config, nil
+}
+
 func genInitialFields(args []FieldPair) map[string]interface{} {
 	fields := make(map[string]interface{})
 	for _, f := range args {
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// ContextExtractor returns the key value pairs carried by ctx to attach to the logs.
+type ContextExtractor func(ctx context.Context) []interface{}
+
+type fieldsKey struct{}
+
+var (
+	extractorMu sync.RWMutex
+	extractors  = []ContextExtractor{traceFields, requestFields}
+)
+
+// RegisterContextExtractor adds an extractor used by WithContext,
+// packages the logger can not depend on register their context values this way.
+func RegisterContextExtractor(fn ContextExtractor) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+	extractors = append(extractors, fn)
+}
+
+// WithFields returns a context carrying request scoped fields, they are logged by WithContext.
+func WithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	merged := make([]interface{}, 0, len(fields)+len(keysAndValues))
+	merged = append(append(merged, fields...), keysAndValues...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithContext returns a logger attaching trace_id, span_id, the app info and the request
+// scoped fields of ctx to every log.
+func WithContext(ctx context.Context) Logger {
+	return l.withContext(ctx)
+}
+
+// Ctx is short for WithContext, e.g. logger.Ctx(ctx).Infow("login", "uid", uid)
+func Ctx(ctx context.Context) Logger {
+	return l.withContext(ctx)
+}
+
+// ContextFields returns the key value pairs of ctx attached to the logs by WithContext.
+func ContextFields(ctx context.Context) []interface{} {
+	extractorMu.RLock()
+	defer extractorMu.RUnlock()
+	var fields []interface{}
+	for _, fn := range extractors {
+		fields = append(fields, fn(ctx)...)
+	}
+	return fields
+}
+
+func (l *logger) withContext(ctx context.Context) *logger {
+	child := l.direct()
+	if fields := ContextFields(ctx); len(fields) > 0 {
+		child.logger = child.logger.With(fields...)
+	}
+	return child
+}
+
+// direct returns a copy of l whose methods are called directly instead of through the
+// package functions, so the caller skip of the config is one frame less.
+func (l *logger) direct() *logger {
+	zl := l.logger.Desugar().WithOptions(zap.AddCallerSkip(-1)).Sugar()
+	return &logger{config: l.config, logger: zl, name: l.name, enabler: l.enabler, redactor: l.redactor, names: l.names, async: l.async}
+}
+
+func traceFields(ctx context.Context) []interface{} {
+	span := trace.SpanContextFromContext(ctx)
+	if !span.IsValid() {
+		return nil
+	}
+	return []interface{}{TraceIDKey, span.TraceID().String(), SpanIDKey, span.SpanID().String()}
+}
+
+func requestFields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	return fields
+}
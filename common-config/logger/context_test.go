@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ctx.log")
+	config := NewProductionConfig()
+	config.OutputPaths = []string{path}
+	base, _ := newLogger(config)
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = WithFields(ctx, "request_id", "r1")
+	ctx = WithFields(ctx, "uid", 7)
+	base.withContext(ctx).Infow("login", "name", "jack")
+	_ = base.logger.Sync()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"span_id":"00f067aa0ba902b7"`,
+		`"request_id":"r1"`,
+		`"uid":7`,
+		`"name":"jack"`,
+		`"caller":"logger/context_test.go`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log %s, want contains %s", data, want)
+		}
+	}
+}
@@ -2,13 +2,34 @@ package logger
 
 import (
 	"context"
-	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 	gLogger "gorm.io/gorm/logger"
 	"gorm.io/gorm/utils"
 )
 
+const (
+	GormTableLabel     = "table"
+	GormOperationLabel = "operation"
+)
+
+// GormQueryDurationHistogram 1.SQL执行时长的直方图, 按表和操作(select/insert/update/delete)区分
+var GormQueryDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gorm_query_duration_seconds",
+	Help:    "SQL执行时长(秒)的直方图",
+	Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+}, []string{GormTableLabel, GormOperationLabel})
+
+// GormSlowQueryCounter 2.超过慢查询阈值的SQL计数
+var GormSlowQueryCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gorm_slow_query_total",
+	Help: "超过慢查询阈值的SQL条数",
+}, []string{GormTableLabel, GormOperationLabel})
+
 type gormLoggerImpl struct {
 	ctx           context.Context
 	log           Logger
@@ -17,7 +38,19 @@ type gormLoggerImpl struct {
 }
 
 // NewGormLogger Logger return singleton logger
+// the sql, rows, elapsed_ms, caller and the fields of ctx are logged as structured fields
 func NewGormLogger(ctx context.Context, log Logger, slowThreshold time.Duration) gLogger.Interface {
+	// the caller is the one of the sql, not the one of the gorm logger
+	if zl, ok := log.(*logger); ok {
+		log = &logger{
+			config:   zl.config,
+			logger:   zl.logger.Desugar().WithOptions(zap.WithCaller(false)).Sugar(),
+			name:     zl.name,
+			enabler:  zl.enabler,
+			redactor: zl.redactor,
+			names:    zl.names,
+		}
+	}
 	return &gormLoggerImpl{
 		ctx:           ctx,
 		log:           log,
@@ -26,57 +59,109 @@ func NewGormLogger(ctx context.Context, log Logger, slowThreshold time.Duration)
 	}
 }
 
+// LogMode returns a copy logging at lvl, the logger shared by the other sessions is not changed
 func (l *gormLoggerImpl) LogMode(lvl gLogger.LogLevel) gLogger.Interface {
-	l.logLvl = lvl
-	// l.log.SetLevel(toLoggerLevel(lvl))
-	return l
+	clone := *l
+	clone.logLvl = lvl
+	if zl, ok := l.log.(*logger); ok && lvl > gLogger.Silent {
+		clone.log = zl.leveled(toLoggerLevel(lvl))
+	}
+	return &clone
+}
+
+func toLoggerLevel(lvl gLogger.LogLevel) Level {
+	switch lvl {
+	case gLogger.Error:
+		return ErrorLevel
+	case gLogger.Warn:
+		return WarnLevel
+	default:
+		return InfoLevel
+	}
+}
+
+// logger returns the logger with the fields of ctx, or the ones of the ctx of NewGormLogger
+func (l *gormLoggerImpl) logger(ctx context.Context) Logger {
+	if ctx == nil {
+		ctx = l.ctx
+	}
+	if fields := ContextFields(ctx); len(fields) > 0 {
+		return l.log.With(fields...)
+	}
+	return l.log
 }
 
 func (l *gormLoggerImpl) Info(ctx context.Context, format string, args ...interface{}) {
-	l.log.Infof(format, args...)
+	if l.logLvl >= gLogger.Info {
+		l.logger(ctx).Infof(format, args...)
+	}
 }
 
 func (l *gormLoggerImpl) Warn(ctx context.Context, format string, args ...interface{}) {
-	l.log.Warnf(format, args...)
+	if l.logLvl >= gLogger.Warn {
+		l.logger(ctx).Warnf(format, args...)
+	}
 }
 
 func (l *gormLoggerImpl) Error(ctx context.Context, format string, args ...interface{}) {
-	l.log.Errorf(format, args...)
+	if l.logLvl >= gLogger.Error {
+		l.logger(ctx).Errorf(format, args...)
+	}
 }
 
-var (
-	traceStr     = "%s\n[%.3fms] [rows:%v] %s"
-	traceWarnStr = "%s %s\n[%.3fms] [rows:%v] %s"
-	traceErrStr  = "%s %s\n[%.3fms] [rows:%v] %s"
-)
-
 // Trace print sql message
+// every query is recorded by GormQueryDurationHistogram and the slow ones by GormSlowQueryCounter
 func (l *gormLoggerImpl) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
-	if l.logLvl > gLogger.Silent {
-		elapsed := time.Since(begin)
-		switch {
-		case err != nil && l.logLvl >= gLogger.Error:
-			sql, rows := fc()
-			if rows == -1 {
-				l.log.Errorf(traceErrStr, utils.FileWithLineNum(), err, float64(elapsed.Nanoseconds())/1e6, "-", sql)
-			} else {
-				l.log.Errorf(traceErrStr, utils.FileWithLineNum(), err, float64(elapsed.Nanoseconds())/1e6, rows, sql)
-			}
-		case elapsed > l.slowThreshold && l.slowThreshold != 0 && l.logLvl >= gLogger.Warn:
-			sql, rows := fc()
-			slowLog := fmt.Sprintf("SLOW SQL >= %v", l.slowThreshold)
-			if rows == -1 {
-				l.log.Warnf(traceWarnStr, utils.FileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, "-", sql)
-			} else {
-				l.log.Warnf(traceWarnStr, utils.FileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, rows, sql)
-			}
-		case l.logLvl == gLogger.Info:
-			sql, rows := fc()
-			if rows == -1 {
-				l.log.Infof(traceStr, utils.FileWithLineNum(), float64(elapsed.Nanoseconds())/1e6, "-", sql)
-			} else {
-				l.log.Infof(traceStr, utils.FileWithLineNum(), float64(elapsed.Nanoseconds())/1e6, rows, sql)
-			}
-		}
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	table, operation := sqlTableOperation(sql)
+	elapsedMs := float64(elapsed.Nanoseconds()) / 1e6
+	GormQueryDurationHistogram.WithLabelValues(table, operation).Observe(elapsed.Seconds())
+	slow := l.slowThreshold != 0 && elapsed > l.slowThreshold
+	if slow {
+		GormSlowQueryCounter.WithLabelValues(table, operation).Inc()
+	}
+	if l.logLvl <= gLogger.Silent {
+		return
+	}
+	fields := []interface{}{
+		"sql", sql,
+		"rows", rows,
+		"elapsed_ms", elapsedMs,
+		"caller", utils.FileWithLineNum(),
+	}
+	switch {
+	case err != nil && l.logLvl >= gLogger.Error:
+		l.logger(ctx).Errorw("gorm query error", append(fields, "error", err)...)
+	case slow && l.logLvl >= gLogger.Warn:
+		l.logger(ctx).Warnw("gorm slow query", append(fields, "slow_threshold", l.slowThreshold.String())...)
+	case l.logLvl == gLogger.Info:
+		l.logger(ctx).Infow("gorm query", fields...)
+	}
+}
+
+var sqlTablePatterns = map[string]*regexp.Regexp{
+	"select": regexp.MustCompile("(?is)\\bfrom\\s+[`\"]?([\\w.]+)"),
+	"insert": regexp.MustCompile("(?is)\\binto\\s+[`\"]?([\\w.]+)"),
+	"update": regexp.MustCompile("(?is)^\\s*update\\s+[`\"]?([\\w.]+)"),
+	"delete": regexp.MustCompile("(?is)\\bfrom\\s+[`\"]?([\\w.]+)"),
+}
+
+// sqlTableOperation returns the table and the operation of sql, the operations other than
+// select, insert, update and delete are other, a table not found is unknown
+func sqlTableOperation(sql string) (table, operation string) {
+	table, operation = "unknown", "unknown"
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return table, operation
+	}
+	op := strings.ToLower(fields[0])
+	re, ok := sqlTablePatterns[op]
+	if !ok {
+		return table, "other"
+	}
+	if m := re.FindStringSubmatch(sql); m != nil {
+		table = strings.ToLower(m[1])
 	}
+	return table, op
 }
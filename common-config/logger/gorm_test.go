@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+	gLogger "gorm.io/gorm/logger"
+)
+
+func counterValue(c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	_ = c.Write(m)
+	return m.GetCounter().GetValue()
+}
+
+func TestGormLogger_Trace(t *testing.T) {
+	config, path := newFileConfig(t, "gorm.log")
+	config.Level = WarnLevel
+	base, _ := newLogger(config)
+	gl := NewGormLogger(context.Background(), base, 100*time.Millisecond)
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	query := func(sql string, rows int64) func() (string, int64) {
+		return func() (string, int64) { return sql, rows }
+	}
+	slowBefore := counterValue(GormSlowQueryCounter.WithLabelValues("user", "select"))
+
+	// the root is at warn, so the info of the shared logger is dropped
+	gl.Trace(ctx, time.Now(), query("SELECT * FROM `user` WHERE id = 1", 1), nil)
+	gl.Trace(ctx, time.Now().Add(-time.Second), query("SELECT * FROM `user` WHERE id = 2", 1), nil)
+	gl.Trace(ctx, time.Now(), query("UPDATE `order` SET state = 1", 0), errors.New("deadlock"))
+	// a debug session logs its queries without changing the shared logger
+	gl.LogMode(gLogger.Info).Trace(ctx, time.Now(), query("INSERT INTO `user` (`name`) VALUES ('a')", 1), nil)
+	gl.Trace(ctx, time.Now(), query("SELECT * FROM `user` WHERE id = 3", 1), nil)
+
+	lines := readLines(t, path)
+	want := []string{
+		`"msg":"gorm slow query","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","sql":"SELECT * FROM ` + "`user`" + ` WHERE id = 2","rows":1`,
+		`"msg":"gorm query error"`,
+		`"msg":"gorm query","trace_id"`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("logged %d lines, want %d: %v", len(lines), len(want), lines)
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("line %d = %s, want %s", i, lines[i], w)
+		}
+	}
+	if !strings.Contains(lines[1], `"error":"deadlock"`) || !strings.Contains(lines[1], `"elapsed_ms":`) {
+		t.Errorf("line 1 = %s, want the error and elapsed_ms", lines[1])
+	}
+	if got := counterValue(GormSlowQueryCounter.WithLabelValues("user", "select")) - slowBefore; got != 1 {
+		t.Errorf("slow queries = %v, want 1", got)
+	}
+}
+
+func Test_sqlTableOperation(t *testing.T) {
+	tests := []struct {
+		sql           string
+		wantTable     string
+		wantOperation string
+	}{
+		{"SELECT count(*) FROM `user_info` WHERE uid = 1", "user_info", "select"},
+		{"insert into orders (id) values (1)", "orders", "insert"},
+		{"UPDATE `user` SET `name`='a'", "user", "update"},
+		{"DELETE FROM `user` WHERE id = 1", "user", "delete"},
+		{"SHOW TABLES", "unknown", "other"},
+		{"", "unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		table, operation := sqlTableOperation(tt.sql)
+		if table != tt.wantTable || operation != tt.wantOperation {
+			t.Errorf("sqlTableOperation(%q) = %s, %s, want %s, %s", tt.sql, table, operation, tt.wantTable, tt.wantOperation)
+		}
+	}
+}
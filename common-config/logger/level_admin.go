@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// RootLoggerName is the name of the global logger.
+const RootLoggerName = "root"
+
+// ErrLoggerNotFound is returned when no logger has the name.
+var ErrLoggerNotFound = errors.New("logger not found")
+
+// leveler is a logger whose level changes at runtime.
+type leveler interface {
+	Level() Level
+	SetLevel(level Level)
+}
+
+// levelRevert is a pending revert of a temporary level change.
+type levelRevert struct {
+	timer    *time.Timer
+	previous Level
+	revertAt time.Time
+}
+
+var (
+	revertMu sync.Mutex
+	reverts  = make(map[string]*levelRevert)
+)
+
+// lookupLeveler returns the logger of name, the empty name is the root logger,
+// the others are the names of the Named children of the global logger and its Config.NamedLevels.
+func lookupLeveler(name string) (leveler, error) {
+	if name == "" || name == RootLoggerName {
+		return l, nil
+	}
+	if n, ok := l.names.lookup(name); ok {
+		return n, nil
+	}
+	return nil, ErrLoggerNotFound
+}
+
+// LevelOf returns the level of the named logger and when a temporary level reverts.
+func LevelOf(name string) (Level, time.Time, error) {
+	lv, err := lookupLeveler(name)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	revertMu.Lock()
+	defer revertMu.Unlock()
+	var revertAt time.Time
+	if r, ok := reverts[normalizeName(name)]; ok {
+		revertAt = r.revertAt
+	}
+	return lv.Level(), revertAt, nil
+}
+
+// SetLevelFor changes the level of the named logger, with a positive ttl the level
+// reverts to the one before the first temporary change once the ttl is exceeded.
+func SetLevelFor(name string, level Level, ttl time.Duration) (time.Time, error) {
+	lv, err := lookupLeveler(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	key := normalizeName(name)
+	revertMu.Lock()
+	defer revertMu.Unlock()
+	previous := lv.Level()
+	if r, ok := reverts[key]; ok {
+		r.timer.Stop()
+		previous = r.previous
+		delete(reverts, key)
+	}
+	lv.SetLevel(level)
+	if ttl <= 0 {
+		return time.Time{}, nil
+	}
+	r := &levelRevert{previous: previous, revertAt: time.Now().Add(ttl)}
+	r.timer = time.AfterFunc(ttl, func() {
+		revertMu.Lock()
+		defer revertMu.Unlock()
+		if reverts[key] != r {
+			return
+		}
+		delete(reverts, key)
+		if lv, err := lookupLeveler(key); err == nil {
+			lv.SetLevel(r.previous)
+			Infof("logger %s level reverted to %s", key, r.previous)
+		}
+	})
+	reverts[key] = r
+	return r.revertAt, nil
+}
+
+func normalizeName(name string) string {
+	if name == "" {
+		return RootLoggerName
+	}
+	return name
+}
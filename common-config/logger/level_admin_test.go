@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetLevelFor(t *testing.T) {
+	SetLevel(InfoLevel)
+	defer SetLevel(InfoLevel)
+	if _, err := SetLevelFor("unknown", DebugLevel, 0); err != ErrLoggerNotFound {
+		t.Fatalf("SetLevelFor() error = %v, want %v", err, ErrLoggerNotFound)
+	}
+	if _, err := SetLevelFor("", WarnLevel, 50*time.Millisecond); err != nil {
+		t.Fatalf("SetLevelFor() error = %v", err)
+	}
+	// a second temporary change reverts to the level before the first one
+	revertAt, err := SetLevelFor(RootLoggerName, DebugLevel, 50*time.Millisecond)
+	if err != nil || revertAt.IsZero() {
+		t.Fatalf("SetLevelFor() = %v, %v", revertAt, err)
+	}
+	if level, got, _ := LevelOf(""); level != DebugLevel || !got.Equal(revertAt) {
+		t.Errorf("LevelOf() = %s, %v, want %s, %v", level, got, DebugLevel, revertAt)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if level, got, _ := LevelOf(""); level != InfoLevel || !got.IsZero() {
+		t.Errorf("LevelOf() = %s, %v, want reverted to %s", level, got, InfoLevel)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"time"
 
 	gormlogger "gorm.io/gorm/logger"
@@ -31,11 +32,20 @@ type Logger interface {
 	Log(level Level, args ...interface{})
 	Logf(level Level, template string, args ...interface{})
 	Logw(level Level, msg string, keysAndValues ...interface{})
+	Named(name string) Logger
+	With(keysAndValues ...interface{}) Logger
 }
 
 type logger struct {
-	config *Config
-	logger *zap.SugaredLogger
+	config   *Config
+	logger   *zap.SugaredLogger
+	name     string
+	enabler  zapcore.LevelEnabler
+	redactor *Redactor
+	// names are the level overrides of the named children, shared by the children of a root logger
+	names *namedLevels
+	// async are the async outputs of a root logger, stopped by Close
+	async []*asyncWriter
 }
 
 var (
@@ -45,8 +55,10 @@ var (
 )
 
 func SetConfig(config *Config) {
+	old := l
 	l, ZapLogger = newLogger(config)
 	gormLogger = NewGormLogger(context.Background(), l, 5*time.Second)
+	_ = old.Close()
 }
 
 func SetLevel(level Level) {
@@ -58,18 +70,21 @@ func GetLevel() Level {
 }
 
 func SetOutputPaths(outputPaths []string) {
+	old := l
 	l.config.OutputPaths = outputPaths
 	l, ZapLogger = newLogger(l.config)
+	_ = old.Close()
 }
 
-func With(key string, value interface{}) {
-	l.config.InitialFields[key] = value
-	l, ZapLogger = newLogger(l.config)
-}
-
+// NewLogger returns a logger of config independent of the global logger, the global logger
+// is returned and logs the error when config can not build one.
 func NewLogger(config *Config) Logger {
-	l, _ := newLogger(l.config)
-	return l
+	nl, _, err := buildLogger(config)
+	if err != nil {
+		l.Errorf("[logger] new logger catch err=%v, the global logger is used", err)
+		return l
+	}
+	return nl.direct()
 }
 
 func GetLogger() Logger {
@@ -80,32 +95,127 @@ func GetGormLogger() gormlogger.Interface {
 	return gormLogger
 }
 
+// newLogger builds the logger of config, the error is written to stderr.
 func newLogger(config *Config) (*logger, *zap.Logger) {
+	nl, zl, err := buildLogger(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error on %s", err)
+		return nil, nil
+	}
+	return nl, zl
+}
+
+// buildLogger builds the logger of config, its caller skip is the one of the package functions.
+func buildLogger(config *Config) (*logger, *zap.Logger, error) {
 	encoderConfig := NewCustomEncoderConfig(config)
+	level := zap.NewAtomicLevelAt(zapcore.Level(config.Level))
 	zapConfig := &zap.Config{
-		Level:             zap.NewAtomicLevelAt(zapcore.Level(config.Level)),
+		// the level is checked by levelCore, named loggers may enable levels below it
+		Level:             zap.NewAtomicLevelAt(zapcore.DebugLevel),
 		Development:       config.Development,
 		DisableCaller:     config.DisableCaller,
 		DisableStacktrace: config.DisableStacktrace,
-		Sampling:          &zap.SamplingConfig{Initial: 100, Thereafter: 100},
 		Encoding:          config.Encoding,
 		EncoderConfig:     encoderConfig,
-		OutputPaths:       config.OutputPaths,
+		OutputPaths:       rotateOutputs(config.OutputPaths, config.Rotate),
 		InitialFields:     config.InitialFields,
 	}
 
-	zapLogger, err := zapConfig.Build()
+	var redactor *Redactor
+	if config.Redact != nil {
+		var err error
+		if redactor, err = NewRedactor(config.Redact); err != nil {
+			return nil, nil, fmt.Errorf("compile redact patterns (%w)", err)
+		}
+	}
+	// async outputs are opened here instead of by zap to buffer their writes
+	var outputCore zapcore.Core
+	var async []*asyncWriter
+	closeAsync := func() {
+		for _, w := range async {
+			_ = w.Close()
+		}
+	}
+	if config.Async != nil {
+		var err error
+		var w *asyncWriter
+		if outputCore, w, err = newOutputCore(config, zapConfig, config.OutputPaths, zapConfig.Level); err != nil {
+			return nil, nil, fmt.Errorf("open outputs (%w)", err)
+		}
+		async = append(async, w)
+		zapConfig.OutputPaths = nil
+	}
+	var errorCore zapcore.Core
+	if len(config.ErrorLevelOutputPaths) > 0 {
+		enabler := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+			return level >= zapcore.ErrorLevel
+		})
+		var err error
+		var w *asyncWriter
+		if errorCore, w, err = newOutputCore(config, zapConfig, config.ErrorLevelOutputPaths, enabler); err != nil {
+			closeAsync()
+			return nil, nil, fmt.Errorf("open error level outputs (%w)", err)
+		}
+		if w != nil {
+			async = append(async, w)
+		}
+	}
+	opts := []zap.Option{zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if outputCore != nil {
+			core = outputCore
+		}
+		core = redactor.wrap(core)
+		if errorCore != nil {
+			core = zapcore.NewTee(core, redactor.wrap(errorCore))
+		}
+		return &levelCore{Core: newSamplingCore(core, config), enabler: level}
+	})}
+	zapLogger, err := zapConfig.Build(opts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error on build zap logger (%s)", err)
-		return nil, nil
+		closeAsync()
+		return nil, nil, fmt.Errorf("build zap logger (%w)", err)
 	}
 	zapLogger = zapLogger.WithOptions(zap.AddCallerSkip(config.CallerSkip))
+	zapConfig.Level = level
 	config.zapConfig = zapConfig
 
 	return &logger{
-		logger: zapLogger.Sugar(),
-		config: config,
-	}, zapLogger
+		logger:   zapLogger.Sugar(),
+		config:   config,
+		enabler:  level,
+		redactor: redactor,
+		names:    newNamedLevels(config.NamedLevels),
+		async:    async,
+	}, zapLogger, nil
+}
+
+// newOutputCore writes the entries enabled by enabler to paths, the writes are buffered
+// by the returned asyncWriter when config.Async is set.
+func newOutputCore(config *Config, zapConfig *zap.Config, paths []string, enabler zapcore.LevelEnabler) (zapcore.Core, *asyncWriter, error) {
+	sink, _, err := zap.Open(rotateOutputs(paths, config.Rotate)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var async *asyncWriter
+	if config.Async != nil {
+		async = newAsyncWriter(sink, config.Async)
+		sink = async
+	}
+	encoder := zapcore.NewJSONEncoder(zapConfig.EncoderConfig)
+	if config.Encoding == "console" {
+		encoder = zapcore.NewConsoleEncoder(zapConfig.EncoderConfig)
+	}
+	// the initial fields are added to the core built by zap before it is wrapped
+	keys := make([]string, 0, len(config.InitialFields))
+	for k := range config.InitialFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]zap.Field, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, zap.Any(k, config.InitialFields[k]))
+	}
+	return zapcore.NewCore(encoder, sink, enabler).With(fields), async, nil
 }
 
 func NewCustomEncoderConfig(conf *Config) zapcore.EncoderConfig {
@@ -142,12 +252,20 @@ func NewCustomEncoderConfig(conf *Config) zapcore.EncoderConfig {
 	}
 }
 
+// SetLevel changes the level of l, for a named logger it overrides the level of the name.
 func (l *logger) SetLevel(level Level) {
+	if l.name != "" {
+		l.names.levelOf(l.name).SetLevel(level)
+		return
+	}
 	l.config.Level = level
 	l.config.zapConfig.Level.SetLevel(zapcore.Level(level))
 }
 
 func (l *logger) Level() Level {
+	if l.name != "" {
+		return l.names.levelOf(l.name).Level()
+	}
 	return l.config.Level
 }
 
@@ -283,3 +401,16 @@ func (l *logger) Logw(level Level, msg string, keysAndValues ...interface{}) {
 func (l *logger) Sync() error {
 	return l.logger.Sync()
 }
+
+// Close writes the buffered entries of the async outputs and stops their goroutines,
+// e.g. when the logger of NewLogger is no longer used, the later entries are written
+// without buffering. SetConfig and SetOutputPaths close the replaced global logger.
+func (l *logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	for _, w := range l.async {
+		_ = w.Close()
+	}
+	return nil
+}
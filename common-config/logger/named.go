@@ -0,0 +1,277 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levelCore filters the entries of a core by the level of its logger,
+// the core built by zap enables every level so named loggers can go below the root level.
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.enabler.Enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+// namedLevel is the level override of the loggers with a name, without an override
+// they follow the level of their parent.
+type namedLevel struct {
+	mu     sync.RWMutex
+	set    bool
+	level  Level
+	parent zapcore.LevelEnabler
+}
+
+// namedLevels are the level overrides of the named loggers of a root logger, each root
+// logger has its own so the loggers of different configs do not change each other.
+type namedLevels struct {
+	mu     sync.Mutex
+	levels map[string]*namedLevel
+}
+
+func newNamedLevels(levels map[string]Level) *namedLevels {
+	n := &namedLevels{levels: make(map[string]*namedLevel, len(levels))}
+	for name, level := range levels {
+		n.levels[name] = &namedLevel{set: true, level: level}
+	}
+	return n
+}
+
+// levelOf returns the level override of name, creating it on first use.
+func (n *namedLevels) levelOf(name string) *namedLevel {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	nl, ok := n.levels[name]
+	if !ok {
+		nl = &namedLevel{}
+		n.levels[name] = nl
+	}
+	return nl
+}
+
+func (n *namedLevels) lookup(name string) (*namedLevel, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	nl, ok := n.levels[name]
+	return nl, ok
+}
+
+// Level returns the override, or the level of the last parent a logger was named under.
+func (n *namedLevel) Level() Level {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.set || n.parent == nil {
+		return n.level
+	}
+	return Level(zapcore.LevelOf(n.parent))
+}
+
+func (n *namedLevel) SetLevel(level Level) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.set = true
+	n.level = level
+}
+
+func (n *namedLevel) enabler(parent zapcore.LevelEnabler) zapcore.LevelEnabler {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.parent = parent
+	return &namedEnabler{named: n, parent: parent}
+}
+
+// namedEnabler enables the levels of the override, or the ones of its parent.
+type namedEnabler struct {
+	named  *namedLevel
+	parent zapcore.LevelEnabler
+}
+
+func (e *namedEnabler) Enabled(level zapcore.Level) bool {
+	e.named.mu.RLock()
+	set, min := e.named.set, e.named.level
+	e.named.mu.RUnlock()
+	if set {
+		return level >= zapcore.Level(min)
+	}
+	return e.parent.Enabled(level)
+}
+
+// Named returns a child logger with name appended to the name of l, its level follows l
+// until it is changed by SetLevelFor or Config.NamedLevels.
+func (l *logger) Named(name string) Logger {
+	return l.named(name)
+}
+
+func (l *logger) named(name string) *logger {
+	full := name
+	if l.name != "" {
+		full = l.name + "." + name
+	}
+	enabler := l.names.levelOf(full).enabler(l.enabler)
+	zl := l.logger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if c, ok := core.(*levelCore); ok {
+			return &levelCore{Core: c.Core, enabler: enabler}
+		}
+		return core
+	})).Named(name)
+	return &logger{config: l.config, logger: zl.Sugar(), name: full, enabler: enabler, redactor: l.redactor, names: l.names}
+}
+
+// leveled returns a child logger with its own level, changing it does not change l.
+func (l *logger) leveled(level Level) *logger {
+	enabler := zap.NewAtomicLevelAt(zapcore.Level(level))
+	zl := l.logger.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if c, ok := core.(*levelCore); ok {
+			return &levelCore{Core: c.Core, enabler: enabler}
+		}
+		return core
+	}))
+	return &logger{config: l.config, logger: zl.Sugar(), name: l.name, enabler: enabler, redactor: l.redactor, names: l.names}
+}
+
+// With returns a child logger adding the key value pairs to every log.
+func (l *logger) With(keysAndValues ...interface{}) Logger {
+	return l.with(keysAndValues...)
+}
+
+func (l *logger) with(keysAndValues ...interface{}) *logger {
+	return &logger{config: l.config, logger: l.logger.With(keysAndValues...), name: l.name, enabler: l.enabler, redactor: l.redactor, names: l.names}
+}
+
+// Named returns a child of the global logger, e.g. var log = logger.Named("kafka_mq").
+// The child follows SetConfig, so it can be created before the config is loaded.
+func Named(name string) Logger {
+	return &globalChild{build: func(root *logger) *logger {
+		return root.named(name)
+	}}
+}
+
+// With returns a child of the global logger adding the key value pairs to every log,
+// the global logger is not changed.
+func With(keysAndValues ...interface{}) Logger {
+	return &globalChild{build: func(root *logger) *logger {
+		return root.with(keysAndValues...)
+	}}
+}
+
+// globalChild is a child logger rebuilt whenever the global logger is replaced.
+type globalChild struct {
+	build   func(root *logger) *logger
+	current atomic.Pointer[childOf]
+}
+
+type childOf struct {
+	root  *logger
+	child *logger
+}
+
+func (g *globalChild) get() *logger {
+	root := l
+	if c := g.current.Load(); c != nil && c.root == root {
+		return c.child
+	}
+	c := &childOf{root: root, child: g.build(root)}
+	g.current.Store(c)
+	return c.child
+}
+
+func (g *globalChild) Named(name string) Logger {
+	return &globalChild{build: func(root *logger) *logger {
+		return g.build(root).named(name)
+	}}
+}
+
+func (g *globalChild) With(keysAndValues ...interface{}) Logger {
+	return &globalChild{build: func(root *logger) *logger {
+		return g.build(root).with(keysAndValues...)
+	}}
+}
+
+func (g *globalChild) Debug(args ...interface{}) {
+	g.get().Debug(args...)
+}
+
+func (g *globalChild) Info(args ...interface{}) {
+	g.get().Info(args...)
+}
+
+func (g *globalChild) Warn(args ...interface{}) {
+	g.get().Warn(args...)
+}
+
+func (g *globalChild) Error(args ...interface{}) {
+	g.get().Error(args...)
+}
+
+func (g *globalChild) Fatal(args ...interface{}) {
+	g.get().Fatal(args...)
+}
+
+func (g *globalChild) Debugf(template string, args ...interface{}) {
+	g.get().Debugf(template, args...)
+}
+
+func (g *globalChild) Infof(template string, args ...interface{}) {
+	g.get().Infof(template, args...)
+}
+
+func (g *globalChild) Warnf(template string, args ...interface{}) {
+	g.get().Warnf(template, args...)
+}
+
+func (g *globalChild) Errorf(template string, args ...interface{}) {
+	g.get().Errorf(template, args...)
+}
+
+func (g *globalChild) Fatalf(template string, args ...interface{}) {
+	g.get().Fatalf(template, args...)
+}
+
+func (g *globalChild) Debugw(msg string, keysAndValues ...interface{}) {
+	g.get().Debugw(msg, keysAndValues...)
+}
+
+func (g *globalChild) Infow(msg string, keysAndValues ...interface{}) {
+	g.get().Infow(msg, keysAndValues...)
+}
+
+func (g *globalChild) Warnw(msg string, keysAndValues ...interface{}) {
+	g.get().Warnw(msg, keysAndValues...)
+}
+
+func (g *globalChild) Errorw(msg string, keysAndValues ...interface{}) {
+	g.get().Errorw(msg, keysAndValues...)
+}
+
+func (g *globalChild) Fatalw(msg string, keysAndValues ...interface{}) {
+	g.get().Fatalw(msg, keysAndValues...)
+}
+
+func (g *globalChild) Log(level Level, args ...interface{}) {
+	g.get().Log(level, args...)
+}
+
+func (g *globalChild) Logf(level Level, template string, args ...interface{}) {
+	g.get().Logf(level, template, args...)
+}
+
+func (g *globalChild) Logw(level Level, msg string, keysAndValues ...interface{}) {
+	g.get().Logw(level, msg, keysAndValues...)
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func newFileConfig(t *testing.T, name string) (*Config, string) {
+	path := filepath.Join(t.TempDir(), name)
+	config := NewProductionConfig()
+	config.OutputPaths = []string{path}
+	return config, path
+}
+
+func readLines(t *testing.T, path string) []string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func TestNewLogger(t *testing.T) {
+	config, path := newFileConfig(t, "new.log")
+	config.Level = WarnLevel
+	log := NewLogger(config)
+	log.Info("dropped")
+	log.Warn("kept")
+	lines := readLines(t, path)
+	if len(lines) != 1 || !strings.Contains(lines[0], "kept") {
+		t.Errorf("NewLogger() wrote %v, want only the warn entry", lines)
+	}
+	if GetLogger() == log {
+		t.Errorf("NewLogger() returned the global logger")
+	}
+}
+
+func TestNewLogger_Caller(t *testing.T) {
+	config, path := newFileConfig(t, "caller.log")
+	log := NewLogger(config)
+	_, file, line, _ := runtime.Caller(0)
+	log.Info("root")
+	log.Named("kafka_mq").Info("named")
+	log.With("topic", "user").Info("with")
+	log.Named("kafka_mq").With("topic", "user").Infow("named with")
+
+	caller := filepath.Base(filepath.Dir(file)) + "/" + filepath.Base(file)
+	lines := readLines(t, path)
+	if len(lines) != 4 {
+		t.Fatalf("logged %d lines, want 4: %v", len(lines), lines)
+	}
+	for i, got := range lines {
+		want := fmt.Sprintf(`"caller":"%s:%d"`, caller, line+1+i)
+		if !strings.Contains(got, want) {
+			t.Errorf("line %d = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestNewLogger_Error(t *testing.T) {
+	config, _ := newFileConfig(t, "error.log")
+	config.Redact = &RedactConfig{Patterns: []string{"("}}
+	if log := NewLogger(config); log != GetLogger() {
+		t.Errorf("NewLogger() = %v, want the global logger for an invalid config", log)
+	}
+}
+
+func TestLogger_Named(t *testing.T) {
+	config, path := newFileConfig(t, "named.log")
+	config.NamedLevels = map[string]Level{"redisapi": ErrorLevel}
+	defer SetConfig(NewDefaultConfig())
+	SetConfig(config)
+	root := l
+	mq := root.Named("kafka_mq")
+	redis := root.Named("redisapi").With("addr", "127.0.0.1:6379")
+
+	mq.Debug("mq debug dropped")
+	mq.Info("mq info")
+	redis.Warn("redis warn dropped")
+	redis.Error("redis error")
+	// only kafka_mq goes to debug, the root stays at info
+	if _, err := SetLevelFor("kafka_mq", DebugLevel, 0); err != nil {
+		t.Fatalf("SetLevelFor() error = %v", err)
+	}
+	mq.Debug("mq debug")
+	root.Debug("root debug dropped")
+
+	lines := readLines(t, path)
+	want := []string{
+		`"logger":"kafka_mq","caller"`,
+		`"logger":"redisapi"`,
+		`"msg":"mq debug"`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("logged %d lines, want %d: %v", len(lines), len(want), lines)
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("line %d = %s, want %s", i, lines[i], w)
+		}
+	}
+	if !strings.Contains(lines[1], `"addr":"127.0.0.1:6379"`) {
+		t.Errorf("line 1 = %s, want the addr field", lines[1])
+	}
+	if level, _, err := LevelOf("redisapi"); err != nil || level != ErrorLevel {
+		t.Errorf("LevelOf() = %v, %v, want %v", level, err, ErrorLevel)
+	}
+}
+
+func TestNewLogger_NamedLevels(t *testing.T) {
+	first, firstPath := newFileConfig(t, "first.log")
+	first.NamedLevels = map[string]Level{"redisapi": ErrorLevel}
+	second, secondPath := newFileConfig(t, "second.log")
+	second.NamedLevels = map[string]Level{"redisapi": DebugLevel}
+	firstRedis := NewLogger(first).Named("redisapi")
+	secondRedis := NewLogger(second).Named("redisapi")
+	secondRedis.(*logger).SetLevel(WarnLevel)
+
+	firstRedis.Warn("first warn dropped")
+	firstRedis.Error("first error")
+	secondRedis.Info("second info dropped")
+	secondRedis.Warn("second warn")
+	if lines := readLines(t, firstPath); len(lines) != 1 || !strings.Contains(lines[0], "first error") {
+		t.Errorf("first.log = %v, want only the error entry", lines)
+	}
+	if lines := readLines(t, secondPath); len(lines) != 1 || !strings.Contains(lines[0], "second warn") {
+		t.Errorf("second.log = %v, want only the warn entry", lines)
+	}
+	// the loggers of NewLogger do not change the named levels of the global logger
+	if _, _, err := LevelOf("redisapi"); err != ErrLoggerNotFound {
+		t.Errorf("LevelOf() error = %v, want %v", err, ErrLoggerNotFound)
+	}
+}
+
+func TestNamed_FollowsSetConfig(t *testing.T) {
+	defer SetConfig(NewDefaultConfig())
+	child := Named("cache").With("component", "test")
+	first, firstPath := newFileConfig(t, "first.log")
+	SetConfig(first)
+	child.Info("first")
+	second, secondPath := newFileConfig(t, "second.log")
+	SetConfig(second)
+	child.Info("second")
+	Info("global")
+
+	if lines := readLines(t, firstPath); len(lines) != 1 || !strings.Contains(lines[0], `"logger":"cache"`) {
+		t.Errorf("first.log = %v, want the child entry", lines)
+	}
+	lines := readLines(t, secondPath)
+	if len(lines) != 2 || !strings.Contains(lines[0], `"component":"test"`) || strings.Contains(lines[1], "component") {
+		t.Errorf("second.log = %v, want the child entry and a global entry without its fields", lines)
+	}
+}
@@ -0,0 +1,326 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the sensitive values in the logs.
+const Redacted = "[REDACTED]"
+
+// RedactConfig is the rules of the sensitive values removed from the logs.
+type RedactConfig struct {
+	// Keys are the field keys and query parameters whose values are redacted, matched
+	// case-insensitively as the whole key or its _ or - separated suffix.
+	Keys []string `json:"keys" yaml:"keys"`
+
+	// Headers are the http headers and gRPC metadata keys whose values are redacted.
+	Headers []string `json:"headers" yaml:"headers"`
+
+	// Patterns are regular expressions of the values redacted in the headers, metadata and
+	// query parameters, and in the strings passed to Redact.
+	Patterns []string `json:"patterns" yaml:"patterns"`
+
+	// ScanMessages also redacts the patterns in every message and string field, it runs
+	// the regular expressions on each entry logged so it is off by default.
+	ScanMessages bool `json:"scanMessages" yaml:"scanMessages"`
+}
+
+// DefaultRedactConfig redacts passwords, tokens, secrets, phone numbers, authorization
+// headers and cookies by their keys, and the bearer tokens, JWTs and phone numbers found
+// in the headers, metadata and query parameters.
+func DefaultRedactConfig() *RedactConfig {
+	return &RedactConfig{
+		Keys:    []string{"authorization", "password", "passwd", "pwd", "token", "secret", "phone", "mobile"},
+		Headers: []string{"authorization", "proxy-authorization", "cookie", "set-cookie", "x-admin-token"},
+		Patterns: []string{
+			`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`,
+			`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+			`\b1[3-9]\d{9}\b`,
+		},
+	}
+}
+
+// Redactor removes the sensitive values of RedactConfig, a nil Redactor keeps every value.
+type Redactor struct {
+	keys     []string
+	headers  map[string]bool
+	patterns []*regexp.Regexp
+	scan     bool
+}
+
+// NewRedactor compiles the rules of config.
+func NewRedactor(config *RedactConfig) (*Redactor, error) {
+	r := &Redactor{headers: make(map[string]bool, len(config.Headers)), scan: config.ScanMessages}
+	for _, key := range config.Keys {
+		r.keys = append(r.keys, strings.ToLower(key))
+	}
+	for _, header := range config.Headers {
+		r.headers[strings.ToLower(header)] = true
+	}
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// IsSensitiveKey reports whether the values of key are redacted.
+func (r *Redactor) IsSensitiveKey(key string) bool {
+	if r == nil {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, k := range r.keys {
+		if key == k || strings.HasSuffix(key, "_"+k) || strings.HasSuffix(key, "-"+k) {
+			return true
+		}
+	}
+	return false
+}
+
+// String replaces the matches of the patterns in s.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// scanString replaces the matches of the patterns in a message or a string field when
+// ScanMessages is set.
+func (r *Redactor) scanString(s string) string {
+	if !r.scan {
+		return s
+	}
+	return r.String(s)
+}
+
+// Header returns a copy of h with the values of the sensitive headers redacted.
+func (r *Redactor) Header(h http.Header) http.Header {
+	if r == nil {
+		return h
+	}
+	return http.Header(r.Metadata(h))
+}
+
+// Metadata returns a copy of md, e.g. gRPC metadata, with the values of the sensitive
+// headers redacted, the others are redacted by the patterns.
+func (r *Redactor) Metadata(md map[string][]string) map[string][]string {
+	if r == nil || md == nil {
+		return md
+	}
+	redacted := make(map[string][]string, len(md))
+	for k, values := range md {
+		copied := make([]string, len(values))
+		for i, v := range values {
+			if r.headers[strings.ToLower(k)] || r.IsSensitiveKey(k) {
+				copied[i] = Redacted
+			} else {
+				copied[i] = r.String(v)
+			}
+		}
+		redacted[k] = copied
+	}
+	return redacted
+}
+
+// Query returns the raw query with the values of the sensitive keys redacted.
+func (r *Redactor) Query(rawQuery string) string {
+	if r == nil || rawQuery == "" {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return r.String(rawQuery)
+	}
+	for k, vs := range values {
+		for i, v := range vs {
+			if r.IsSensitiveKey(k) {
+				vs[i] = Redacted
+			} else {
+				vs[i] = r.String(v)
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// Field redacts a structured field, the sensitive keys and with ScanMessages the string values,
+// the values of zap.Any, zap.Reflect, zap.Object and zap.Stringer are redacted the same way.
+func (r *Redactor) Field(f zapcore.Field) zapcore.Field {
+	if r == nil || f.Key == "" {
+		return f
+	}
+	if r.IsSensitiveKey(f.Key) {
+		return zap.String(f.Key, Redacted)
+	}
+	switch f.Type {
+	case zapcore.StringType, zapcore.ErrorType, zapcore.ByteStringType, zapcore.StringerType:
+		if !r.scan {
+			return f
+		}
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.String(f.String)
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			return zap.String(f.Key, r.String(err.Error()))
+		}
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			return zap.ByteString(f.Key, []byte(r.String(string(b))))
+		}
+	case zapcore.StringerType:
+		if s, ok := stringOf(f.Interface); ok {
+			return zap.String(f.Key, r.String(s))
+		}
+	case zapcore.ReflectType:
+		switch v := f.Interface.(type) {
+		case http.Header:
+			return zap.Any(f.Key, r.Header(v))
+		case map[string][]string:
+			return zap.Any(f.Key, r.Metadata(v))
+		}
+		// the other values are redacted as their json, the way they are encoded
+		b, err := json.Marshal(f.Interface)
+		if err != nil {
+			return f
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return f
+		}
+		return zap.Any(f.Key, r.value(v))
+	case zapcore.ObjectMarshalerType:
+		if m, ok := f.Interface.(zapcore.ObjectMarshaler); ok {
+			enc := zapcore.NewMapObjectEncoder()
+			if err := m.MarshalLogObject(enc); err != nil {
+				return f
+			}
+			return zap.Any(f.Key, r.value(enc.Fields))
+		}
+	case zapcore.ArrayMarshalerType:
+		if m, ok := f.Interface.(zapcore.ArrayMarshaler); ok {
+			enc := zapcore.NewMapObjectEncoder()
+			if err := enc.AddArray(f.Key, m); err != nil {
+				return f
+			}
+			return zap.Any(f.Key, r.value(enc.Fields[f.Key]))
+		}
+	}
+	return f
+}
+
+// value redacts the sensitive keys and the strings of a decoded value in place.
+func (r *Redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.scanString(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			if r.IsSensitiveKey(k) || r.headers[strings.ToLower(k)] {
+				v[k] = Redacted
+			} else {
+				v[k] = r.value(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = r.value(e)
+		}
+	}
+	return v
+}
+
+// stringOf calls the String method of a zap.Stringer value, false when it panics like
+// on a nil pointer, zap reports the panic then.
+func stringOf(v interface{}) (s string, ok bool) {
+	stringer, ok := v.(fmt.Stringer)
+	if !ok {
+		return "", false
+	}
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return stringer.String(), true
+}
+
+func (r *Redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = r.Field(f)
+	}
+	return redacted
+}
+
+// wrap redacts the entries written to core.
+func (r *Redactor) wrap(core zapcore.Core) zapcore.Core {
+	if r == nil {
+		return core
+	}
+	return &redactCore{Core: core, redactor: r}
+}
+
+// redactCore redacts the messages and the fields of the entries.
+type redactCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redactor.fields(fields)), redactor: c.redactor}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.scanString(entry.Message)
+	return c.Core.Write(entry, c.redactor.fields(fields))
+}
+
+// GetRedactor returns the redactor of the global logger, nil when it does not redact.
+func GetRedactor() *Redactor {
+	return l.redactor
+}
+
+// RedactHeader redacts h with the rules of the global logger, e.g. before dumping a request.
+func RedactHeader(h http.Header) http.Header {
+	return l.redactor.Header(h)
+}
+
+// RedactMetadata redacts gRPC metadata with the rules of the global logger.
+func RedactMetadata(md map[string][]string) map[string][]string {
+	return l.redactor.Metadata(md)
+}
+
+// RedactQuery redacts a raw url query with the rules of the global logger.
+func RedactQuery(rawQuery string) string {
+	return l.redactor.Query(rawQuery)
+}
+
+// Redact redacts s with the patterns of the global logger.
+func Redact(s string) string {
+	return l.redactor.String(s)
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type credential struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func (c credential) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.User)
+	enc.AddString("password", c.Password)
+	return nil
+}
+
+type dsn string
+
+func (d dsn) String() string {
+	return "mysql://root@127.0.0.1/user?auth=Bearer " + string(d)
+}
+
+func TestRedactor(t *testing.T) {
+	config, path := newFileConfig(t, "redact.log")
+	config.Redact.ScanMessages = true
+	log, _ := newLogger(config)
+	jwt := "eyJhbGciOiJIUzI1NiJ9.eyJ1aWQiOjF9.c2lnbmF0dXJl"
+	log.Infow("login", "user", "alice", "password", "p@ss", "user_phone", "13812345678")
+	log.With("access_token", "abc").Info("with token")
+	log.Errorf("call failed header=Bearer %s", jwt)
+	log.Errorw("call failed", "error", errors.New("invalid token "+jwt), "ip", "10.0.0.1")
+
+	b := strings.Join(readLines(t, path), "\n")
+	for _, leaked := range []string{"p@ss", "13812345678", `"abc"`, jwt} {
+		if strings.Contains(b, leaked) {
+			t.Errorf("log leaked %s:\n%s", leaked, b)
+		}
+	}
+	for _, kept := range []string{`"user":"alice"`, `"ip":"10.0.0.1"`, `"password":"[REDACTED]"`, "header=[REDACTED]"} {
+		if !strings.Contains(b, kept) {
+			t.Errorf("log misses %s:\n%s", kept, b)
+		}
+	}
+}
+
+func TestRedactor_Field(t *testing.T) {
+	config, path := newFileConfig(t, "redact_field.log")
+	config.Redact.ScanMessages = true
+	_, log := newLogger(config)
+	headers := map[string]string{"Authorization": "Bearer abc", "Accept": "application/json"}
+	log.Info("fields",
+		zap.Any("headers", headers),
+		zap.Any("http_header", http.Header{"Cookie": {"sid=s3cr3t"}, "Accept": {"text/html"}}),
+		zap.Reflect("login", credential{User: "alice", Password: "p@ss"}),
+		zap.Object("object", credential{User: "bob", Password: "hunter2"}),
+		zap.Any("list", []map[string]interface{}{{"token": "xyz", "page": 2}}),
+		zap.Stringer("dsn", dsn("t0ken")),
+	)
+
+	b := strings.Join(readLines(t, path), "\n")
+	for _, leaked := range []string{"Bearer abc", "s3cr3t", "p@ss", "hunter2", "xyz", "t0ken"} {
+		if strings.Contains(b, leaked) {
+			t.Errorf("log leaked %s:\n%s", leaked, b)
+		}
+	}
+	for _, kept := range []string{`"Accept":"application/json"`, `"Accept":["text/html"]`, `"user":"alice"`, `"user":"bob"`, `"page":2`, `"dsn":"mysql://root@127.0.0.1/user?auth=[REDACTED]"`} {
+		if !strings.Contains(b, kept) {
+			t.Errorf("log misses %s:\n%s", kept, b)
+		}
+	}
+	if headers["Authorization"] != "Bearer abc" {
+		t.Errorf("Field() changed the original map")
+	}
+}
+
+func TestRedactor_Default(t *testing.T) {
+	config, path := newFileConfig(t, "redact_default.log")
+	log, _ := newLogger(config)
+	log.Infow("order 13812345678 paid", "password", "p@ss", "remark", "call 13812345678")
+
+	// the default config redacts by the keys, the messages and string fields are not scanned
+	b := strings.Join(readLines(t, path), "\n")
+	for _, kept := range []string{`"msg":"order 13812345678 paid"`, `"password":"[REDACTED]"`, `"remark":"call 13812345678"`} {
+		if !strings.Contains(b, kept) {
+			t.Errorf("log misses %s:\n%s", kept, b)
+		}
+	}
+}
+
+func TestRedactor_Request(t *testing.T) {
+	r, err := NewRedactor(DefaultRedactConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := http.Header{"Authorization": {"Bearer abc"}, "X-Admin-Token": {"secret"}, "Accept": {"application/json"}}
+	got := r.Header(h)
+	if got.Get("Authorization") != Redacted || got.Get("X-Admin-Token") != Redacted || got.Get("Accept") != "application/json" {
+		t.Errorf("Header() = %v", got)
+	}
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Errorf("Header() changed the original header")
+	}
+	if got := r.Query("token=abc&page=2"); got != "page=2&token=%5BREDACTED%5D" {
+		t.Errorf("Query() = %s", got)
+	}
+	if _, err := NewRedactor(&RedactConfig{Patterns: []string{"("}}); err == nil {
+		t.Errorf("NewRedactor() want error of invalid pattern")
+	}
+	var nilRedactor *Redactor
+	if got := nilRedactor.String("Bearer abc"); got != "Bearer abc" {
+		t.Errorf("nil Redactor String() = %s", got)
+	}
+}
+
+func BenchmarkRedactor(b *testing.B) {
+	for _, scan := range []bool{false, true} {
+		b.Run(fmt.Sprintf("scan=%v", scan), func(b *testing.B) {
+			config := DefaultRedactConfig()
+			config.ScanMessages = scan
+			r, err := NewRedactor(config)
+			if err != nil {
+				b.Fatal(err)
+			}
+			core := r.wrap(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.DebugLevel))
+			log := zap.New(core)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				log.Info("order paid by the user after the payment callback", zap.String("order_id", "202310170001"), zap.String("remark", "delivered to the front desk"))
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RotateScheme is the output url scheme of rotated files,
+// e.g. rotate:///var/log/app.log?max_size=100&max_backups=7&compress=true
+const RotateScheme = "rotate"
+
+const (
+	megabyte         = 1024 * 1024
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+)
+
+// RotateConfig rotates the file outputs by size and time.
+type RotateConfig struct {
+	// MaxSize is the maximum size in megabytes of a file before it gets rotated, 0 means no limit.
+	MaxSize int `json:"maxSize" yaml:"maxSize"`
+
+	// Interval rotates the file when the local time crosses a multiple of the interval, e.g.
+	// at the local midnight for 24h, 0 disables it.
+	Interval time.Duration `json:"interval" yaml:"interval"`
+
+	// MaxBackups is the maximum number of rotated files to retain, 0 retains all of them.
+	MaxBackups int `json:"maxBackups" yaml:"maxBackups"`
+
+	// MaxAge is the maximum time to retain rotated files, 0 retains them forever.
+	MaxAge time.Duration `json:"maxAge" yaml:"maxAge"`
+
+	// Compress gzips the rotated files.
+	Compress bool `json:"compress" yaml:"compress"`
+}
+
+func init() {
+	if err := zap.RegisterSink(RotateScheme, newRotateSink); err != nil {
+		panic(err)
+	}
+}
+
+// rotateOutputs replaces the file paths of outputs with rotate urls.
+func rotateOutputs(outputs []string, rotate *RotateConfig) []string {
+	if rotate == nil {
+		return outputs
+	}
+	paths := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		paths = append(paths, rotate.url(output))
+	}
+	return paths
+}
+
+// url is the rotate url of a file path, stdout, stderr and other urls are kept.
+func (c *RotateConfig) url(path string) string {
+	if path == "stdout" || path == "stderr" || strings.Contains(path, "://") || strings.HasPrefix(path, RotateScheme+":") {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	query := url.Values{}
+	if c.MaxSize > 0 {
+		query.Set("max_size", strconv.Itoa(c.MaxSize))
+	}
+	if c.Interval > 0 {
+		query.Set("interval", c.Interval.String())
+	}
+	if c.MaxBackups > 0 {
+		query.Set("max_backups", strconv.Itoa(c.MaxBackups))
+	}
+	if c.MaxAge > 0 {
+		query.Set("max_age", c.MaxAge.String())
+	}
+	if c.Compress {
+		query.Set("compress", "true")
+	}
+	u := url.URL{Scheme: RotateScheme, Path: filepath.ToSlash(path), RawQuery: query.Encode()}
+	return u.String()
+}
+
+// parseRotateConfig reads the rotate config from the query of a rotate url.
+func parseRotateConfig(query url.Values) (RotateConfig, error) {
+	var (
+		c   RotateConfig
+		err error
+	)
+	if v := query.Get("max_size"); v != "" {
+		if c.MaxSize, err = strconv.Atoi(v); err != nil {
+			return c, fmt.Errorf("invalid max_size %q: %w", v, err)
+		}
+	}
+	if v := query.Get("interval"); v != "" {
+		if c.Interval, err = time.ParseDuration(v); err != nil {
+			return c, fmt.Errorf("invalid interval %q: %w", v, err)
+		}
+	}
+	if v := query.Get("max_backups"); v != "" {
+		if c.MaxBackups, err = strconv.Atoi(v); err != nil {
+			return c, fmt.Errorf("invalid max_backups %q: %w", v, err)
+		}
+	}
+	if v := query.Get("max_age"); v != "" {
+		if c.MaxAge, err = time.ParseDuration(v); err != nil {
+			return c, fmt.Errorf("invalid max_age %q: %w", v, err)
+		}
+	}
+	if v := query.Get("compress"); v != "" {
+		if c.Compress, err = strconv.ParseBool(v); err != nil {
+			return c, fmt.Errorf("invalid compress %q: %w", v, err)
+		}
+	}
+	return c, nil
+}
+
+var (
+	rotatorsMu sync.Mutex
+	// rotators shares one writer per file, loggers rebuilt by SetConfig keep rotating the same file
+	rotators = make(map[string]*RotateWriter)
+)
+
+func newRotateSink(u *url.URL) (zap.Sink, error) {
+	c, err := parseRotateConfig(u.Query())
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.FromSlash(u.Path)
+	if path == "" {
+		return nil, fmt.Errorf("rotate url %q has no file path", u.String())
+	}
+	rotatorsMu.Lock()
+	defer rotatorsMu.Unlock()
+	w, ok := rotators[path]
+	if !ok {
+		w = NewRotateWriter(path, c)
+		rotators[path] = w
+		return w, nil
+	}
+	w.mu.Lock()
+	w.config = c
+	w.mu.Unlock()
+	return w, nil
+}
+
+// RotateWriter is a file writer rotating the file by size and time,
+// rotated files are renamed to name-<time>.ext and optionally gzipped.
+type RotateWriter struct {
+	path   string
+	config RotateConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+	now    func() time.Time
+}
+
+// NewRotateWriter returns a writer of the file at path, the file is opened on the first write.
+func NewRotateWriter(path string, config RotateConfig) *RotateWriter {
+	return &RotateWriter{path: path, config: config, now: time.Now}
+}
+
+// Write writes p to the file, rotating it first when p would exceed the max size
+// or the current interval has passed.
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync commits the file to stable storage.
+func (w *RotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Rotate rotates the file immediately.
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	return w.rotate()
+}
+
+// Close closes the file and waits for the pending compression and cleanup,
+// the file is reopened on the next write.
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	err := w.close()
+	w.mu.Unlock()
+	w.millWg.Wait()
+	return err
+}
+
+func (w *RotateWriter) shouldRotate(n int64) bool {
+	if w.config.MaxSize > 0 && w.size > 0 && w.size+n > int64(w.config.MaxSize)*megabyte {
+		return true
+	}
+	if w.config.Interval > 0 && !w.period(w.now()).Equal(w.period(w.openedAt)) {
+		return true
+	}
+	return false
+}
+
+// period returns the start of the interval of t, Truncate counts from the zero time in UTC
+// so t is shifted by its zone offset to start the periods at the local boundaries.
+func (w *RotateWriter) period(t time.Time) time.Time {
+	_, offset := t.Zone()
+	d := time.Duration(offset) * time.Second
+	return t.Add(d).Truncate(w.config.Interval).Add(-d)
+}
+
+// open appends to the existing file, a file left from a previous period is rotated on the next write.
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = w.now()
+	if w.size > 0 {
+		w.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (w *RotateWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotateWriter) rotate() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+	if _, err := os.Stat(w.path); err == nil {
+		if err := os.Rename(w.path, w.backupName(w.now())); err != nil {
+			return err
+		}
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.openedAt = w.now()
+	w.millWg.Add(1)
+	go w.mill()
+	return nil
+}
+
+// backupName is an unused name of a rotated file.
+func (w *RotateWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	for {
+		name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+		_, err := os.Stat(name)
+		_, gzErr := os.Stat(name + compressSuffix)
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func (w *RotateWriter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.path)
+	base := filepath.Base(w.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// backups returns the rotated files, newest first.
+func (w *RotateWriter) backups() ([]string, error) {
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, compressSuffix), ext)
+		if _, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix)); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(dir, name))
+	}
+	// the time format sorts lexically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// mill compresses the rotated files and removes the ones over max backups or max age.
+func (w *RotateWriter) mill() {
+	defer w.millWg.Done()
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+	w.mu.Lock()
+	config := w.config
+	w.mu.Unlock()
+
+	names, err := w.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list rotated logs of %s catch err=%v\n", w.path, err)
+		return
+	}
+	_, prefix, ext := w.nameParts()
+	var keep []string
+	for i, name := range names {
+		expired := false
+		if config.MaxAge > 0 {
+			stamp := strings.TrimPrefix(strings.TrimSuffix(strings.TrimSuffix(filepath.Base(name), compressSuffix), ext), prefix)
+			t, _ := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+			expired = w.now().Sub(t) > config.MaxAge
+		}
+		if expired || (config.MaxBackups > 0 && i >= config.MaxBackups) {
+			if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "remove rotated log %s catch err=%v\n", name, err)
+			}
+			continue
+		}
+		keep = append(keep, name)
+	}
+	if !config.Compress {
+		return
+	}
+	for _, name := range keep {
+		if strings.HasSuffix(name, compressSuffix) {
+			continue
+		}
+		if err := compressFile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "compress rotated log %s catch err=%v\n", name, err)
+		}
+	}
+}
+
+// compressFile gzips name to name.gz and removes name.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(name + compressSuffix)
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/format"
+)
+
+func TestRotateWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	w := NewRotateWriter(path, RotateConfig{MaxSize: 1, Interval: time.Hour, MaxBackups: 2, Compress: true})
+	w.now = func() time.Time { return now }
+	line := []byte(strings.Repeat("x", megabyte/2-1) + "\n")
+	// size rotation: every second line starts a new file
+	for i := 0; i < 6; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		now = now.Add(time.Second)
+	}
+	// time rotation: the next hour starts a new file
+	now = now.Add(time.Hour)
+	if _, err := w.Write([]byte("next hour\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	backups, err := w.backups()
+	if err != nil {
+		t.Fatalf("backups() error = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("backups() = %v, want 2 files", backups)
+	}
+	for _, name := range backups {
+		if !strings.HasSuffix(name, ".log"+compressSuffix) {
+			t.Errorf("backup %s is not compressed", name)
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(gz)
+		_ = f.Close()
+		if len(b) != 2*len(line) {
+			t.Errorf("backup %s has %d bytes, want %d", name, len(b), 2*len(line))
+		}
+	}
+	if b, _ := os.ReadFile(path); string(b) != "next hour\n" {
+		t.Errorf("current file = %q, want %q", b, "next hour\n")
+	}
+}
+
+func TestRotateWriter_LocalInterval(t *testing.T) {
+	cst := time.FixedZone("CST", 8*3600)
+	w := NewRotateWriter(filepath.Join(t.TempDir(), "app.log"), RotateConfig{Interval: 24 * time.Hour})
+	tests := []struct {
+		name     string
+		openedAt time.Time
+		now      time.Time
+		want     bool
+	}{
+		{
+			name:     "local midnight",
+			openedAt: time.Date(2024, 5, 1, 23, 59, 0, 0, cst),
+			now:      time.Date(2024, 5, 2, 0, 1, 0, 0, cst),
+			want:     true,
+		},
+		{
+			name:     "utc midnight",
+			openedAt: time.Date(2024, 5, 2, 7, 59, 0, 0, cst),
+			now:      time.Date(2024, 5, 2, 8, 1, 0, 0, cst),
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w.openedAt = tt.openedAt
+			w.now = func() time.Time { return tt.now }
+			if got := w.shouldRotate(0); got != tt.want {
+				t.Errorf("shouldRotate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotateWriter_MaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	w := NewRotateWriter(path, RotateConfig{MaxAge: 48 * time.Hour})
+	w.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("Rotate() error = %v", err)
+		}
+		now = now.Add(24 * time.Hour)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the first backup is 72h old at the last rotation
+	backups, _ := w.backups()
+	if len(backups) != 2 {
+		t.Errorf("backups() = %v, want 2 files", backups)
+	}
+}
+
+func TestNewLogger_ErrorLevelOutput(t *testing.T) {
+	dir := t.TempDir()
+	config := NewProductionConfig(FieldPair{"service", "user"})
+	config.OutputPaths = []string{filepath.Join(dir, "all.log")}
+	config.ErrorLevelOutputPaths = []string{filepath.Join(dir, "error.log")}
+	config.Rotate = &RotateConfig{MaxSize: 10}
+	l, _ := newLogger(config)
+	l.Info("info message")
+	l.Error("error message")
+	_ = l.Sync()
+
+	all, _ := os.ReadFile(filepath.Join(dir, "all.log"))
+	errs, _ := os.ReadFile(filepath.Join(dir, "error.log"))
+	if !strings.Contains(string(all), "info message") || !strings.Contains(string(all), "error message") {
+		t.Errorf("all.log = %s, want both entries", all)
+	}
+	if strings.Contains(string(errs), "info message") || !strings.Contains(string(errs), "error message") || !strings.Contains(string(errs), `"service":"user"`) {
+		t.Errorf("error.log = %s, want only the error entry with the initial fields", errs)
+	}
+}
+
+func TestNewConfigFromLogConfig(t *testing.T) {
+	config, err := NewConfigFromLogConfig(format.LogConfig{
+		Level:          "warn",
+		Output:         "/var/log/user.log",
+		ErrorOutput:    "/var/log/user.error.log",
+		MaxSize:        100,
+		RotateInterval: "daily",
+		MaxAge:         7,
+		Compress:       true,
+	})
+	if err != nil {
+		t.Fatalf("NewConfigFromLogConfig() error = %v", err)
+	}
+	want := RotateConfig{MaxSize: 100, Interval: 24 * time.Hour, MaxAge: 7 * 24 * time.Hour, Compress: true}
+	if config.Level != WarnLevel || config.Rotate == nil || *config.Rotate != want {
+		t.Errorf("NewConfigFromLogConfig() = %v, %+v", config.Level, config.Rotate)
+	}
+	got := rotateOutputs(append(config.OutputPaths, "stdout"), config.Rotate)
+	if got[0] != "rotate:///var/log/user.log?compress=true&interval=24h0m0s&max_age=168h0m0s&max_size=100" || got[1] != "stdout" {
+		t.Errorf("rotateOutputs() = %v", got)
+	}
+	if _, err := NewConfigFromLogConfig(format.LogConfig{Level: "verbose"}); err == nil {
+		t.Errorf("NewConfigFromLogConfig() want error of invalid level")
+	}
+}
@@ -0,0 +1,241 @@
+package logger
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/ratelimit"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultSamplingTick is the period the sampling counters are reset.
+	DefaultSamplingTick = time.Second
+	// DefaultSuppressedInterval is the minimum interval between the reports of the suppressed entries.
+	DefaultSuppressedInterval = time.Minute
+	// SuppressedMessage is the message of the entry reporting the suppressed entries.
+	SuppressedMessage = "log messages suppressed"
+
+	samplingCounters = 4096
+	numLevels        = int(FatalLevel-DebugLevel) + 1
+)
+
+// SamplingPolicy limits the entries of a level or a message.
+//
+// Sampling logs the first Initial entries of the same message every Tick and every
+// Thereafter-th after that, Initial 1 and Thereafter 0 dedups a message within a tick.
+// Rate limiting logs a Burst of entries and then Rate entries per second, dropping the rest,
+// Burst defaults to Rate rounded up, at least 1.
+type SamplingPolicy struct {
+	Tick       time.Duration `json:"tick" yaml:"tick"`
+	Initial    int           `json:"initial" yaml:"initial"`
+	Thereafter int           `json:"thereafter" yaml:"thereafter"`
+	Rate       float64       `json:"rate" yaml:"rate"`
+	Burst      int           `json:"burst" yaml:"burst"`
+}
+
+// DefaultSampling samples the first 100 entries of a message per second and every 100th after that.
+func DefaultSampling() map[Level]*SamplingPolicy {
+	sampling := make(map[Level]*SamplingPolicy, numLevels)
+	for level := DebugLevel; level <= FatalLevel; level++ {
+		sampling[level] = &SamplingPolicy{Tick: DefaultSamplingTick, Initial: 100, Thereafter: 100}
+	}
+	return sampling
+}
+
+type counter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+func (c *counter) inc(now time.Time, tick time.Duration) uint64 {
+	tn := now.UnixNano()
+	resetAt := c.resetAt.Load()
+	if resetAt > tn {
+		return c.count.Add(1)
+	}
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, tn+tick.Nanoseconds()) {
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// limiter applies a SamplingPolicy.
+type limiter struct {
+	policy   SamplingPolicy
+	counters []counter
+	bucket   *ratelimit.Bucket
+}
+
+func newLimiter(policy SamplingPolicy) *limiter {
+	if policy.Tick <= 0 {
+		policy.Tick = DefaultSamplingTick
+	}
+	lm := &limiter{policy: policy}
+	if policy.Rate > 0 {
+		lm.bucket = ratelimit.NewBucket(policy.Rate, policy.Burst, time.Now())
+	}
+	if policy.Initial > 0 {
+		lm.counters = make([]counter, samplingCounters)
+	}
+	return lm
+}
+
+// sample reports whether the entry passes the sampling.
+func (lm *limiter) sample(entry zapcore.Entry) bool {
+	if lm.counters == nil {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(entry.Message))
+	n := lm.counters[h.Sum32()%samplingCounters].inc(entry.Time, lm.policy.Tick)
+	if n <= uint64(lm.policy.Initial) {
+		return true
+	}
+	return lm.policy.Thereafter > 0 && (n-uint64(lm.policy.Initial))%uint64(lm.policy.Thereafter) == 0
+}
+
+// allow reports whether the entry is within the rate, a token bucket of Burst refilled at Rate.
+func (lm *limiter) allow(now time.Time) bool {
+	if lm.bucket == nil {
+		return true
+	}
+	ok, _ := lm.bucket.Allow(now)
+	return ok
+}
+
+type messageLimiter struct {
+	prefix string
+	*limiter
+}
+
+// suppression counts the dropped entries of a logger and reports them periodically.
+type suppression struct {
+	base       zapcore.Core
+	interval   time.Duration
+	now        func() time.Time
+	sampled    [numLevels]atomic.Int64
+	limited    [numLevels]atomic.Int64
+	lastReport atomic.Int64
+}
+
+// samplingCore drops the entries over the policies of their message or level.
+type samplingCore struct {
+	zapcore.Core
+	levels     [numLevels]*limiter
+	messages   []messageLimiter
+	suppressed *suppression
+}
+
+func newSamplingCore(core zapcore.Core, config *Config) zapcore.Core {
+	sampling := config.Sampling
+	if sampling == nil {
+		sampling = DefaultSampling()
+	}
+	if len(sampling) == 0 && len(config.MessageSampling) == 0 {
+		return core
+	}
+	interval := config.SuppressedInterval
+	if interval <= 0 {
+		interval = DefaultSuppressedInterval
+	}
+	c := &samplingCore{Core: core, suppressed: &suppression{base: core, interval: interval, now: time.Now}}
+	c.suppressed.lastReport.Store(time.Now().UnixNano())
+	for level, policy := range sampling {
+		if policy != nil && level >= DebugLevel && level <= FatalLevel {
+			c.levels[level-DebugLevel] = newLimiter(*policy)
+		}
+	}
+	for prefix, policy := range config.MessageSampling {
+		if policy != nil {
+			c.messages = append(c.messages, messageLimiter{prefix: prefix, limiter: newLimiter(*policy)})
+		}
+	}
+	// the longest prefix matches first
+	sort.Slice(c.messages, func(i, j int) bool {
+		return len(c.messages[i].prefix) > len(c.messages[j].prefix)
+	})
+	return c
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+func (c *samplingCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return ce
+	}
+	defer c.suppressed.reportEvery()
+	lm := c.limiterOf(entry)
+	if lm == nil {
+		return c.Core.Check(entry, ce)
+	}
+	index := int(entry.Level - zapcore.DebugLevel)
+	if index < 0 || index >= numLevels {
+		index = int(ErrorLevel - DebugLevel)
+	}
+	if !lm.sample(entry) {
+		c.suppressed.sampled[index].Add(1)
+		return ce
+	}
+	if !lm.allow(entry.Time) {
+		c.suppressed.limited[index].Add(1)
+		return ce
+	}
+	return c.Core.Check(entry, ce)
+}
+
+func (c *samplingCore) limiterOf(entry zapcore.Entry) *limiter {
+	for _, m := range c.messages {
+		if strings.HasPrefix(entry.Message, m.prefix) {
+			return m.limiter
+		}
+	}
+	index := int(entry.Level - zapcore.DebugLevel)
+	if index < 0 || index >= numLevels {
+		return nil
+	}
+	return c.levels[index]
+}
+
+// Sync reports the suppressed entries not reported yet before syncing.
+func (c *samplingCore) Sync() error {
+	c.suppressed.report()
+	return c.Core.Sync()
+}
+
+// reportEvery reports the suppressed entries once the interval since the last report passed.
+func (s *suppression) reportEvery() {
+	now := s.now().UnixNano()
+	last := s.lastReport.Load()
+	if now-last < s.interval.Nanoseconds() || !s.lastReport.CompareAndSwap(last, now) {
+		return
+	}
+	s.report()
+}
+
+// report writes a warn entry per level with the number of suppressed entries since the last report.
+func (s *suppression) report() {
+	for i := 0; i < numLevels; i++ {
+		sampled, limited := s.sampled[i].Swap(0), s.limited[i].Swap(0)
+		if sampled == 0 && limited == 0 {
+			continue
+		}
+		entry := zapcore.Entry{Level: zapcore.WarnLevel, Time: s.now(), Message: SuppressedMessage}
+		fields := []zapcore.Field{
+			zap.String("suppressed_level", (DebugLevel + Level(i)).String()),
+			zap.Int64("suppressed", sampled+limited),
+			zap.Int64("sampled", sampled),
+			zap.Int64("rate_limited", limited),
+		}
+		_ = s.base.Write(entry, fields)
+	}
+}
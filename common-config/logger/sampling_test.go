@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func countLines(lines []string, substr string) int {
+	n := 0
+	for _, line := range lines {
+		if strings.Contains(line, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSampling(t *testing.T) {
+	config, path := newFileConfig(t, "sampling.log")
+	config.Sampling = map[Level]*SamplingPolicy{
+		InfoLevel:  {Tick: time.Hour, Initial: 2, Thereafter: 3},
+		ErrorLevel: {Tick: time.Hour, Initial: 1},
+	}
+	config.MessageSampling = map[string]*SamplingPolicy{
+		"kafka_mq send message err": {Rate: 0.001, Burst: 3},
+	}
+	log, _ := newLogger(config)
+	for i := 0; i < 10; i++ {
+		log.Info("sampled")
+		log.Warn("not sampled")
+		log.Error("dedup")
+		log.Errorf("kafka_mq send message err=%d", i)
+	}
+	log.Info("other message")
+	_ = log.Sync()
+
+	lines := readLines(t, path)
+	tests := []struct {
+		msg  string
+		want int
+	}{
+		// 1, 2, then every 3rd: 5, 8
+		{`"msg":"sampled"`, 4},
+		{`"msg":"not sampled"`, 10},
+		{`"msg":"dedup"`, 1},
+		{`"msg":"kafka_mq send message err=`, 3},
+		{`"msg":"other message"`, 1},
+	}
+	for _, tt := range tests {
+		if got := countLines(lines, tt.msg); got != tt.want {
+			t.Errorf("%s logged %d times, want %d", tt.msg, got, tt.want)
+		}
+	}
+	for _, want := range []string{
+		`"suppressed_level":"info","suppressed":6,"sampled":6,"rate_limited":0`,
+		`"suppressed_level":"error","suppressed":16,"sampled":9,"rate_limited":7`,
+	} {
+		if countLines(lines, want) != 1 {
+			t.Errorf("want a %s entry with %s in %v", SuppressedMessage, want, lines)
+		}
+	}
+}
+
+func TestSampling_ReportEvery(t *testing.T) {
+	config, path := newFileConfig(t, "report.log")
+	config.Sampling = map[Level]*SamplingPolicy{InfoLevel: {Initial: 1}}
+	config.SuppressedInterval = 50 * time.Millisecond
+	log, _ := newLogger(config)
+	log.Info("dropped")
+	log.Info("dropped")
+	time.Sleep(60 * time.Millisecond)
+	// the next entry past the interval reports the dropped one
+	log.Warn("next")
+	if lines := readLines(t, path); countLines(lines, SuppressedMessage) != 1 {
+		t.Errorf("logged %v, want a %s entry", lines, SuppressedMessage)
+	}
+}
+
+func TestSampling_DefaultBurst(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		want int
+	}{
+		{"fraction", 0.001, 1},
+		{"rounded up", 2.5, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, path := newFileConfig(t, "burst.log")
+			config.Sampling = map[Level]*SamplingPolicy{InfoLevel: {Rate: tt.rate}}
+			log, _ := newLogger(config)
+			for i := 0; i < 10; i++ {
+				log.Info("limited")
+			}
+			if got := countLines(readLines(t, path), `"msg":"limited"`); got != tt.want {
+				t.Errorf("logged %d times, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampling_Disabled(t *testing.T) {
+	config, path := newFileConfig(t, "disabled.log")
+	config.Sampling = map[Level]*SamplingPolicy{}
+	log, _ := newLogger(config)
+	for i := 0; i < 200; i++ {
+		log.Info("same")
+	}
+	if lines := readLines(t, path); len(lines) != 200 {
+		t.Errorf("logged %d lines, want 200", len(lines))
+	}
+}
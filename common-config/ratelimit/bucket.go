@@ -0,0 +1,57 @@
+// Package ratelimit is the token bucket of the log sampling and the gRPC server rate limits.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket refills rate tokens per second up to burst, a call takes a token.
+type Bucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full bucket, burst defaults to rate rounded up, at least 1.
+func NewBucket(rate float64, burst int, now time.Time) *Bucket {
+	if rate > 0 && burst <= 0 {
+		// without a burst the bucket never has a token and every call is rejected
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &Bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// Allow takes a token, or returns the time until the next token.
+func (b *Bucket) Allow(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.rate <= 0 {
+		return false, time.Second
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// Full reports whether the bucket is full, so it can be dropped without changing the limit.
+func (b *Bucket) Full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
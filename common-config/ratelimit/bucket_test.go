@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Now()
+	b := NewBucket(10, 2, now)
+	tests := []struct {
+		name           string
+		elapsed        time.Duration
+		wantOK         bool
+		wantRetryAfter time.Duration
+	}{
+		{"burst", 0, true, 0},
+		{"burst", 0, true, 0},
+		{"empty", 0, false, 100 * time.Millisecond},
+		{"half refilled", 50 * time.Millisecond, false, 50 * time.Millisecond},
+		{"refilled", 100 * time.Millisecond, true, 0},
+	}
+	for _, tt := range tests {
+		ok, retryAfter := b.Allow(now.Add(tt.elapsed))
+		if ok != tt.wantOK || (retryAfter-tt.wantRetryAfter).Abs() > time.Millisecond {
+			t.Errorf("%s: Allow() = %v, %v, want %v, %v", tt.name, ok, retryAfter, tt.wantOK, tt.wantRetryAfter)
+		}
+	}
+	if b.Full(now.Add(100 * time.Millisecond)) {
+		t.Errorf("Full() = true after taking a token")
+	}
+	if !b.Full(now.Add(time.Second)) {
+		t.Errorf("Full() = false once refilled")
+	}
+}
+
+func TestBucket_DefaultBurst(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		want  int
+	}{
+		{"fraction", 0.5, 0, 1},
+		{"rate", 100, 0, 100},
+		{"set", 100, 5, 5},
+	}
+	for _, tt := range tests {
+		now := time.Now()
+		b := NewBucket(tt.rate, tt.burst, now)
+		got := 0
+		for i := 0; i < 200; i++ {
+			if ok, _ := b.Allow(now); ok {
+				got++
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: allowed %d calls, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,17 +2,39 @@ package net
 
 import (
 	"context"
+	"net/url"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/segmentio/ksuid"
 	"github.com/weiqiangxu/micro_project/common-config/logger"
+	"github.com/weiqiangxu/micro_project/net/registry"
+	"github.com/weiqiangxu/micro_project/net/transport"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	// DefaultPhase is the phase of servers registered with Server.
+	DefaultPhase = "default"
+	// DefaultStopTimeout is the graceful stop timeout of a server.
+	DefaultStopTimeout = 10 * time.Second
+	// DefaultHookTimeout is the timeout of a lifecycle hook.
+	DefaultHookTimeout = 10 * time.Second
+	// DefaultRegistrarTimeout is the timeout of registering and deregistering the app.
+	DefaultRegistrarTimeout = 10 * time.Second
+)
+
+var (
+	// ErrStopTimeout is returned when a server does not stop within its timeout.
+	ErrStopTimeout = errors.New("server stop timeout")
+	// ErrHookTimeout is returned when a hook does not return within its timeout.
+	ErrHookTimeout = errors.New("hook timeout")
+)
+
 // AppInfo is application context value.
 type AppInfo interface {
 	ID() string
@@ -22,16 +44,22 @@ type AppInfo interface {
 
 // App is an application components lifecycle manager
 type App struct {
-	opts   options
-	ctx    context.Context
-	cancel func()
+	opts      options
+	ctx       context.Context
+	cancel    func()
+	mu        sync.Mutex
+	endpoints []*url.URL
+	instance  *registry.ServiceInstance
 }
 
 // New create an application lifecycle manager.
 func New(opts ...Option) *App {
 	options := options{
-		ctx:  context.Background(),
-		sigs: []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT},
+		ctx:              context.Background(),
+		sigs:             []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT},
+		stopTimeout:      DefaultStopTimeout,
+		hookTimeout:      DefaultHookTimeout,
+		registrarTimeout: DefaultRegistrarTimeout,
 	}
 	options.id = ksuid.New().String()
 	for _, o := range opts {
@@ -54,8 +82,29 @@ func (a *App) Name() string { return a.opts.name }
 // Version returns app version.
 func (a *App) Version() string { return a.opts.version }
 
-// Run executes all OnStart hooks registered with the application's Lifecycle.
+// Endpoints returns the endpoints the servers are bound to.
+func (a *App) Endpoints() []*url.URL {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*url.URL(nil), a.endpoints...)
+}
+
+// Run starts the phases in order, waiting for the listeners of every phase to be bound
+// before starting the next one, and stops them in reverse order once the app is stopped.
 func (a *App) Run() error {
+	// a signal during the startup stops the phases already started in reverse order
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, a.opts.sigs...)
+	defer signal.Stop(c)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-c:
+			_ = a.Stop()
+		case <-done:
+		}
+	}()
 	ctx := NewContext(a.ctx, a)
 	if a.opts.agentAddr != "" {
 		tp, err := configAgent(ctx, a.opts.agentAddr, a.opts.name, a.opts.version, a.opts.attributes...)
@@ -68,35 +117,63 @@ func (a *App) Run() error {
 			}
 		}()
 	}
+	if err := a.runHooks(ctx, "before start", a.opts.beforeStart, true); err != nil {
+		return err
+	}
 	eg, ctx := errgroup.WithContext(ctx)
-	wg := sync.WaitGroup{}
-	for _, srv := range a.opts.servers {
-		srv := srv
-		eg.Go(func() error {
-			<-ctx.Done() // wait for stop signal
-			return srv.Stop(ctx)
-		})
-		wg.Add(1)
-		eg.Go(func() error {
-			wg.Done()
-			return srv.Start(ctx)
-		})
-	}
-	wg.Wait()
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, a.opts.sigs...)
-	eg.Go(func() error {
-		for {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-c:
-				return a.Stop()
-			}
+	started := 0
+	var startErr error
+	for _, p := range a.opts.phases {
+		if ctx.Err() != nil {
+			break
+		}
+		logger.Infof("[app] starting phase %s", p.name)
+		if startErr = a.listen(p); startErr != nil {
+			_ = a.Stop()
+			break
+		}
+		wg := sync.WaitGroup{}
+		for _, srv := range p.servers {
+			srv := srv
+			wg.Add(1)
+			eg.Go(func() error {
+				wg.Done()
+				return srv.Start(ctx)
+			})
+		}
+		wg.Wait()
+		started++
+	}
+	phases := a.opts.phases[:started]
+	if ctx.Err() == nil {
+		if startErr = a.runHooks(ctx, "after start", a.opts.afterStart, true); startErr != nil {
+			_ = a.Stop()
+		}
+	}
+	if startErr == nil && ctx.Err() == nil {
+		if startErr = a.register(ctx); startErr != nil {
+			_ = a.Stop()
+		}
+	}
+	<-ctx.Done() // wait for stop signal
+	stopCtx := NewContext(context.Background(), a)
+	deregisterErr := a.deregister(stopCtx)
+	beforeStopErr := a.runHooks(stopCtx, "before stop", a.opts.beforeStop, false)
+	stopErr := a.stopPhases(phases)
+	var serveErr error
+	// servers that did not stop in time may never return from Start
+	if !errors.Is(stopErr, ErrStopTimeout) {
+		if err := eg.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+			serveErr = err
+		}
+	}
+	// the logger is flushed last so the logs of the stop are written before Run returns
+	afterStop := append(append(make([]Hook, 0, len(a.opts.afterStop)+1), a.opts.afterStop...), syncLogger)
+	afterStopErr := a.runHooks(stopCtx, "after stop", afterStop, false)
+	for _, err := range []error{startErr, serveErr, stopErr, deregisterErr, beforeStopErr, afterStopErr} {
+		if err != nil {
+			return err
 		}
-	})
-	if err := eg.Wait(); err != nil && !errors.Is(err, context.Canceled) {
-		return err
 	}
 	return nil
 }
@@ -109,6 +186,187 @@ func (a *App) Stop() error {
 	return nil
 }
 
+// register registers the app instance with its endpoints into the registrar.
+func (a *App) register(ctx context.Context) error {
+	if a.opts.registrar == nil {
+		return nil
+	}
+	instance := a.buildInstance()
+	ctx, cancel := context.WithTimeout(ctx, a.opts.registrarTimeout)
+	defer cancel()
+	if err := a.opts.registrar.Register(ctx, instance); err != nil {
+		logger.Errorf("[app] register %s catch err=%v", instance.Name, err)
+		return errors.Wrap(err, "register")
+	}
+	logger.Infof("[app] registered %s %s on %v", instance.Name, instance.ID, instance.Endpoints)
+	a.mu.Lock()
+	a.instance = instance
+	a.mu.Unlock()
+	return nil
+}
+
+// deregister removes the registered app instance from the registrar.
+func (a *App) deregister(ctx context.Context) error {
+	a.mu.Lock()
+	instance := a.instance
+	a.instance = nil
+	a.mu.Unlock()
+	if instance == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.opts.registrarTimeout)
+	defer cancel()
+	if err := a.opts.registrar.Deregister(ctx, instance); err != nil {
+		logger.Errorf("[app] deregister %s catch err=%v", instance.Name, err)
+		return errors.Wrap(err, "deregister")
+	}
+	logger.Infof("[app] deregistered %s %s", instance.Name, instance.ID)
+	return nil
+}
+
+// buildInstance returns the service instance of the app.
+func (a *App) buildInstance() *registry.ServiceInstance {
+	endpoints := make([]string, 0)
+	for _, u := range a.Endpoints() {
+		endpoints = append(endpoints, u.String())
+	}
+	return &registry.ServiceInstance{
+		ID:        a.opts.id,
+		Name:      a.opts.name,
+		Version:   a.opts.version,
+		Metadata:  a.opts.metadata,
+		Endpoints: endpoints,
+	}
+}
+
+// listen binds the listeners of the phase servers and records their endpoints.
+func (a *App) listen(p phase) error {
+	for i, srv := range p.servers {
+		e, ok := srv.(transport.Endpointer)
+		if !ok {
+			continue
+		}
+		u, err := e.Endpoint()
+		if err != nil {
+			logger.Errorf("[app] phase %s server %T listen catch err=%v", p.name, srv, err)
+			// 同一阶段的服务不会启动, 关闭已经绑定的监听
+			a.closeListeners(p.name, p.servers[:i])
+			return errors.Wrapf(err, "phase %s server %T listen", p.name, srv)
+		}
+		logger.Infof("[app] phase %s server %T ready on %s", p.name, srv, u)
+		a.mu.Lock()
+		a.endpoints = append(a.endpoints, u)
+		a.mu.Unlock()
+	}
+	return nil
+}
+
+// closeListeners closes the listeners bound by servers that are never started.
+func (a *App) closeListeners(phase string, servers []transport.Server) {
+	for _, srv := range servers {
+		c, ok := srv.(transport.ListenerCloser)
+		if !ok {
+			continue
+		}
+		if err := c.CloseListener(); err != nil {
+			logger.Errorf("[app] phase %s server %T close listener catch err=%v", phase, srv, err)
+		}
+	}
+}
+
+// stopPhases stops the phases in reverse order, servers of one phase concurrently.
+func (a *App) stopPhases(phases []phase) error {
+	var err error
+	for i := len(phases) - 1; i >= 0; i-- {
+		p := phases[i]
+		logger.Infof("[app] stopping phase %s", p.name)
+		eg := errgroup.Group{}
+		for _, srv := range p.servers {
+			srv := srv
+			eg.Go(func() error {
+				return a.stopServer(p.name, srv)
+			})
+		}
+		if e := eg.Wait(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// stopServer stops srv and gives up waiting once its stop timeout is exceeded.
+func (a *App) stopServer(phase string, srv transport.Server) error {
+	timeout := a.opts.stopTimeout
+	if d, ok := a.opts.stopTimeouts[srv]; ok {
+		timeout = d
+	}
+	ctx := NewContext(context.Background(), a)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Stop(ctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			logger.Errorf("[app] phase %s server %T stop catch err=%v", phase, srv, err)
+		}
+		return err
+	case <-ctx.Done():
+		logger.Errorf("[app] phase %s server %T force stopped after %s", phase, srv, timeout)
+		return errors.Wrapf(ErrStopTimeout, "phase %s server %T", phase, srv)
+	}
+}
+
+// runHooks runs the hooks of a stage one by one, each within the hook timeout.
+// With abort the first failing hook skips the rest, otherwise the first error is returned.
+func (a *App) runHooks(ctx context.Context, stage string, hooks []Hook, abort bool) error {
+	var err error
+	for i, hook := range hooks {
+		e := a.runHook(ctx, hook)
+		if errors.Is(e, context.Canceled) {
+			// the app is stopping, the remaining hooks are skipped
+			return err
+		}
+		if e != nil {
+			logger.Errorf("[app] %s hook %d catch err=%v", stage, i, e)
+			if err == nil {
+				err = errors.Wrapf(e, "%s hook %d", stage, i)
+			}
+			if abort {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// runHook runs hook and gives up waiting once the hook timeout is exceeded.
+func (a *App) runHook(ctx context.Context, hook Hook) error {
+	if a.opts.hookTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.opts.hookTimeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- hook(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrHookTimeout
+		}
+		return ctx.Err()
+	}
+}
+
 type appKey struct{}
 
 // NewContext returns a new Context that carries value.
@@ -2,7 +2,16 @@ package net
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"os"
+	"reflect"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/net/registry"
 )
 
 func TestNew(t *testing.T) {
@@ -46,3 +55,265 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+type testServer struct {
+	name   string
+	events *[]string
+	mu     *sync.Mutex
+	block  bool
+}
+
+func (s *testServer) record(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*s.events = append(*s.events, event+":"+s.name)
+}
+
+func (s *testServer) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *testServer) Stop(ctx context.Context) error {
+	if s.block {
+		<-make(chan struct{})
+	}
+	s.record("stop")
+	return nil
+}
+
+func TestApp_RunPhases(t *testing.T) {
+	var events []string
+	mu := &sync.Mutex{}
+	storage := &testServer{name: "storage", events: &events, mu: mu}
+	event := &testServer{name: "event", events: &events, mu: mu}
+	grpc := &testServer{name: "grpc", events: &events, mu: mu}
+	app := New(
+		Phase("storage", storage),
+		Phase("event", event),
+		Phase("grpc", grpc),
+	)
+	time.AfterFunc(50*time.Millisecond, func() { _ = app.Stop() })
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []string{"stop:grpc", "stop:event", "stop:storage"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Run() events = %v, want %v", events, want)
+	}
+}
+
+func TestApp_RunStopTimeout(t *testing.T) {
+	var events []string
+	mu := &sync.Mutex{}
+	slow := &testServer{name: "slow", events: &events, mu: mu, block: true}
+	fast := &testServer{name: "fast", events: &events, mu: mu}
+	app := New(
+		Phase("fast", fast),
+		Phase("slow", slow),
+		ServerStopTimeout(20*time.Millisecond, slow),
+	)
+	time.AfterFunc(50*time.Millisecond, func() { _ = app.Stop() })
+	err := app.Run()
+	if !errors.Is(err, ErrStopTimeout) {
+		t.Fatalf("Run() error = %v, want %v", err, ErrStopTimeout)
+	}
+	want := []string{"stop:fast"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Run() events = %v, want %v", events, want)
+	}
+}
+
+func TestApp_RunHooks(t *testing.T) {
+	var events []string
+	mu := &sync.Mutex{}
+	hook := func(name string) Hook {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, name)
+			return nil
+		}
+	}
+	srv := &testServer{name: "grpc", events: &events, mu: mu}
+	var app *App
+	app = New(
+		Server(srv),
+		BeforeStart(hook("before start")),
+		AfterStart(hook("after start"), func(ctx context.Context) error {
+			time.AfterFunc(20*time.Millisecond, func() { _ = app.Stop() })
+			return nil
+		}),
+		BeforeStop(hook("before stop")),
+		AfterStop(hook("after stop")),
+	)
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []string{"before start", "after start", "before stop", "stop:grpc", "after stop"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Run() events = %v, want %v", events, want)
+	}
+}
+
+func TestApp_RunSignalDuringStart(t *testing.T) {
+	var events []string
+	mu := &sync.Mutex{}
+	storage := &testServer{name: "storage", events: &events, mu: mu}
+	grpc := &testServer{name: "grpc", events: &events, mu: mu}
+	app := New(
+		Phase("storage", storage),
+		Phase("grpc", grpc),
+		Signal(syscall.SIGUSR1),
+		AfterStart(func(ctx context.Context) error {
+			// the signal arrives while the app is still starting
+			if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+				return err
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	)
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []string{"stop:grpc", "stop:storage"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Run() events = %v, want %v", events, want)
+	}
+}
+
+func TestApp_RunHookError(t *testing.T) {
+	hookErr := errors.New("warm up failed")
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{
+			name:    "before start error aborts run",
+			opts:    []Option{BeforeStart(func(ctx context.Context) error { return hookErr })},
+			wantErr: hookErr,
+		},
+		{
+			name:    "after start error stops app",
+			opts:    []Option{AfterStart(func(ctx context.Context) error { return hookErr })},
+			wantErr: hookErr,
+		},
+		{
+			name: "after start timeout stops app",
+			opts: []Option{
+				HookTimeout(10 * time.Millisecond),
+				AfterStart(func(ctx context.Context) error {
+					<-make(chan struct{})
+					return nil
+				}),
+			},
+			wantErr: ErrHookTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			srv := &testServer{name: "grpc", events: &events, mu: &sync.Mutex{}}
+			app := New(append(tt.opts, Server(srv))...)
+			if err := app.Run(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type testEndpointServer struct {
+	testServer
+	endpoint *url.URL
+	err      error
+}
+
+func (s *testEndpointServer) Endpoint() (*url.URL, error) {
+	return s.endpoint, s.err
+}
+
+func (s *testEndpointServer) CloseListener() error {
+	s.record("close listener")
+	return nil
+}
+
+func TestApp_RunEndpoints(t *testing.T) {
+	var events []string
+	mu := &sync.Mutex{}
+	endpoint := &url.URL{Scheme: "grpc", Host: "127.0.0.1:9191"}
+	srv := &testEndpointServer{testServer: testServer{name: "grpc", events: &events, mu: mu}, endpoint: endpoint}
+	var app *App
+	app = New(
+		Server(srv),
+		AfterStart(func(ctx context.Context) error {
+			if got := app.Endpoints(); !reflect.DeepEqual(got, []*url.URL{endpoint}) {
+				t.Errorf("Endpoints() = %v, want %v", got, []*url.URL{endpoint})
+			}
+			return app.Stop()
+		}),
+	)
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+}
+
+func TestApp_RunListenError(t *testing.T) {
+	var events []string
+	mu := &sync.Mutex{}
+	listenErr := errors.New("address already in use")
+	storage := &testServer{name: "storage", events: &events, mu: mu}
+	http := &testEndpointServer{testServer: testServer{name: "http", events: &events, mu: mu}, endpoint: &url.URL{Scheme: "http", Host: "127.0.0.1:8181"}}
+	grpc := &testEndpointServer{testServer: testServer{name: "grpc", events: &events, mu: mu}, err: listenErr}
+	app := New(
+		Phase("storage", storage),
+		Phase("grpc", http, grpc),
+	)
+	if err := app.Run(); !errors.Is(err, listenErr) {
+		t.Fatalf("Run() error = %v, want %v", err, listenErr)
+	}
+	// 绑定失败的阶段不会启动, 已经绑定的监听被关闭
+	if want := []string{"close listener:http", "stop:storage"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("Run() events = %v, want %v", events, want)
+	}
+}
+
+func TestApp_RunRegistrar(t *testing.T) {
+	var events []string
+	mu := &sync.Mutex{}
+	endpoint := &url.URL{Scheme: "grpc", Host: "127.0.0.1:9191"}
+	srv := &testEndpointServer{testServer: testServer{name: "grpc", events: &events, mu: mu}, endpoint: endpoint}
+	r := registry.NewMemory()
+	app := New(
+		ID("1"),
+		Name("user-service"),
+		Version("v0.0.1"),
+		Server(srv),
+		Registrar(r),
+	)
+	time.AfterFunc(50*time.Millisecond, func() {
+		got, _ := r.GetService(context.Background(), "user-service")
+		want := []*registry.ServiceInstance{{ID: "1", Name: "user-service", Version: "v0.0.1", Endpoints: []string{endpoint.String()}}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetService() = %v, want %v", got, want)
+		}
+		_ = app.Stop()
+	})
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, _ := r.GetService(context.Background(), "user-service"); len(got) != 0 {
+		t.Errorf("GetService() = %v, want deregistered", got)
+	}
+}
+
+func Test_appFields(t *testing.T) {
+	app := New(ID("1"), Name("user-service"), Version("v0.0.1"))
+	want := []interface{}{AppIDKey, "1", AppNameKey, "user-service", AppVersionKey, "v0.0.1"}
+	if got := appFields(NewContext(context.Background(), app)); !reflect.DeepEqual(got, want) {
+		t.Errorf("appFields() = %v, want %v", got, want)
+	}
+	if got := appFields(context.Background()); got != nil {
+		t.Errorf("appFields() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,33 @@
+package net
+
+import (
+	"context"
+
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+)
+
+const (
+	AppIDKey      = "app_id"
+	AppNameKey    = "app_name"
+	AppVersionKey = "app_version"
+)
+
+func init() {
+	logger.RegisterContextExtractor(appFields)
+}
+
+// appFields returns the app info of ctx to attach to the logs.
+func appFields(ctx context.Context) []interface{} {
+	info, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []interface{}{AppIDKey, info.ID(), AppNameKey, info.Name(), AppVersionKey, info.Version()}
+}
+
+// syncLogger flushes the buffered logs when the app stops.
+func syncLogger(ctx context.Context) error {
+	// syncing stdout returns invalid argument on most platforms, it is not an error
+	_ = logger.Sync()
+	return nil
+}
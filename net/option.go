@@ -3,7 +3,9 @@ package net
 import (
 	"context"
 	"os"
+	"time"
 
+	"github.com/weiqiangxu/micro_project/net/registry"
 	"github.com/weiqiangxu/micro_project/net/transport"
 )
 
@@ -12,16 +14,46 @@ type Option func(o *options)
 
 type TracerFn func(o *options) error
 
+// Hook is a function run at a stage of the application lifecycle.
+type Hook func(ctx context.Context) error
+
 // options is an application options.
 type options struct {
-	id         string
-	name       string
-	version    string
-	ctx        context.Context
-	sigs       []os.Signal
-	servers    []transport.Server
-	attributes []KeyValue
-	agentAddr  string
+	id               string
+	name             string
+	version          string
+	ctx              context.Context
+	sigs             []os.Signal
+	phases           []phase
+	stopTimeout      time.Duration
+	stopTimeouts     map[transport.Server]time.Duration
+	beforeStart      []Hook
+	afterStart       []Hook
+	beforeStop       []Hook
+	afterStop        []Hook
+	hookTimeout      time.Duration
+	metadata         map[string]string
+	registrar        registry.Registrar
+	registrarTimeout time.Duration
+	attributes       []KeyValue
+	agentAddr        string
+}
+
+// phase is a named group of servers started together.
+type phase struct {
+	name    string
+	servers []transport.Server
+}
+
+// setPhase replaces the servers of the named phase or appends a new one.
+func (o *options) setPhase(name string, srv []transport.Server) {
+	for i := range o.phases {
+		if o.phases[i].name == name {
+			o.phases[i].servers = srv
+			return
+		}
+	}
+	o.phases = append(o.phases, phase{name: name, servers: srv})
 }
 
 // ID with service id.
@@ -39,14 +71,42 @@ func Version(version string) Option {
 	return func(o *options) { o.version = version }
 }
 
+// Metadata with service metadata.
+func Metadata(md map[string]string) Option {
+	return func(o *options) { o.metadata = md }
+}
+
 // Context with service context.
 func Context(ctx context.Context) Option {
 	return func(o *options) { o.ctx = ctx }
 }
 
-// Server with transport servers.
+// Server with transport servers, started in the default phase.
 func Server(srv ...transport.Server) Option {
-	return func(o *options) { o.servers = srv }
+	return func(o *options) { o.setPhase(DefaultPhase, srv) }
+}
+
+// Phase with a named group of transport servers.
+// Phases start in the order they are registered and stop in reverse order.
+func Phase(name string, srv ...transport.Server) Option {
+	return func(o *options) { o.setPhase(name, srv) }
+}
+
+// StopTimeout with the default graceful stop timeout of every server.
+func StopTimeout(timeout time.Duration) Option {
+	return func(o *options) { o.stopTimeout = timeout }
+}
+
+// ServerStopTimeout with the graceful stop timeout of the given servers.
+func ServerStopTimeout(timeout time.Duration, srv ...transport.Server) Option {
+	return func(o *options) {
+		if o.stopTimeouts == nil {
+			o.stopTimeouts = make(map[transport.Server]time.Duration)
+		}
+		for _, s := range srv {
+			o.stopTimeouts[s] = timeout
+		}
+	}
 }
 
 // Signal with exit signals.
@@ -54,6 +114,42 @@ func Signal(sigs ...os.Signal) Option {
 	return func(o *options) { o.sigs = sigs }
 }
 
+// BeforeStart with hooks run before any server starts, an error aborts Run.
+func BeforeStart(fn ...Hook) Option {
+	return func(o *options) { o.beforeStart = append(o.beforeStart, fn...) }
+}
+
+// AfterStart with hooks run after all servers started, an error stops the application.
+func AfterStart(fn ...Hook) Option {
+	return func(o *options) { o.afterStart = append(o.afterStart, fn...) }
+}
+
+// BeforeStop with hooks run before the servers stop.
+func BeforeStop(fn ...Hook) Option {
+	return func(o *options) { o.beforeStop = append(o.beforeStop, fn...) }
+}
+
+// AfterStop with hooks run after all servers stopped.
+func AfterStop(fn ...Hook) Option {
+	return func(o *options) { o.afterStop = append(o.afterStop, fn...) }
+}
+
+// HookTimeout with the timeout of every single hook.
+func HookTimeout(timeout time.Duration) Option {
+	return func(o *options) { o.hookTimeout = timeout }
+}
+
+// Registrar with service registry, the app registers itself once all servers started
+// and deregisters before the servers stop.
+func Registrar(r registry.Registrar) Option {
+	return func(o *options) { o.registrar = r }
+}
+
+// RegistrarTimeout with registrar timeout.
+func RegistrarTimeout(timeout time.Duration) Option {
+	return func(o *options) { o.registrarTimeout = timeout }
+}
+
 // Tracing with agent address and span attributes
 func Tracing(agentAddr string, attributes ...KeyValue) Option {
 	return func(o *options) {
@@ -0,0 +1,188 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultFileWatchInterval is the interval a file watcher polls the file.
+const DefaultFileWatchInterval = time.Second
+
+var (
+	_ Registrar = (*File)(nil)
+	_ Discovery = (*File)(nil)
+)
+
+// FileOption is file registry option.
+type FileOption func(f *File)
+
+// WithWatchInterval with the interval the watchers poll the file.
+func WithWatchInterval(interval time.Duration) FileOption {
+	return func(f *File) {
+		f.interval = interval
+	}
+}
+
+// File is a registry stored as json in a local file, it lets processes of one host
+// discover each other. Writes of one File are serialized, concurrent writers from
+// several processes may overwrite each other.
+type File struct {
+	mu       sync.Mutex
+	path     string
+	interval time.Duration
+}
+
+// NewFile create a registry stored in the file of path.
+func NewFile(path string, opts ...FileOption) *File {
+	f := &File{path: path, interval: DefaultFileWatchInterval}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
+}
+
+// Register the registration, an instance with the same ID is replaced.
+func (f *File) Register(ctx context.Context, service *ServiceInstance) error {
+	return f.update(func(services map[string][]*ServiceInstance) {
+		list := removeInstance(services[service.Name], service.ID)
+		services[service.Name] = append(list, service)
+	})
+}
+
+// Deregister the registration.
+func (f *File) Deregister(ctx context.Context, service *ServiceInstance) error {
+	return f.update(func(services map[string][]*ServiceInstance) {
+		list := removeInstance(services[service.Name], service.ID)
+		if len(list) == 0 {
+			delete(services, service.Name)
+			return
+		}
+		services[service.Name] = list
+	})
+}
+
+// GetService return the service instances according to the service name.
+func (f *File) GetService(ctx context.Context, serviceName string) ([]*ServiceInstance, error) {
+	services, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return services[serviceName], nil
+}
+
+// Watch creates a watcher polling the file according to the service name.
+func (f *File) Watch(ctx context.Context, serviceName string) (Watcher, error) {
+	return &fileWatcher{
+		f:       f,
+		name:    serviceName,
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// update loads the file, applies fn and writes the file back atomically.
+func (f *File) update(fn func(services map[string][]*ServiceInstance)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	services, err := f.load()
+	if err != nil {
+		return err
+	}
+	fn(services)
+	data, err := json.MarshalIndent(services, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// load reads the services of the file, a missing file has no services.
+func (f *File) load() (map[string][]*ServiceInstance, error) {
+	services := make(map[string][]*ServiceInstance)
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return services, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return services, nil
+	}
+	if err := json.Unmarshal(data, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// removeInstance returns list without the instance of id.
+func removeInstance(list []*ServiceInstance, id string) []*ServiceInstance {
+	result := make([]*ServiceInstance, 0, len(list))
+	for _, s := range list {
+		if s.ID != id {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+type fileWatcher struct {
+	f       *File
+	name    string
+	last    []*ServiceInstance
+	first   bool
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (w *fileWatcher) Next() ([]*ServiceInstance, error) {
+	if !w.first {
+		w.first = true
+		list, err := w.f.GetService(context.Background(), w.name)
+		if err != nil {
+			return nil, err
+		}
+		w.last = list
+		return list, nil
+	}
+	ticker := time.NewTicker(w.f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopped:
+			return nil, ErrWatcherStopped
+		case <-ticker.C:
+			list, err := w.f.GetService(context.Background(), w.name)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(list, w.last) {
+				w.last = list
+				return list, nil
+			}
+		}
+	}
+}
+
+func (w *fileWatcher) Stop() error {
+	w.once.Do(func() {
+		close(w.stopped)
+	})
+	return nil
+}
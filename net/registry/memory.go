@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	_ Registrar = (*Memory)(nil)
+	_ Discovery = (*Memory)(nil)
+)
+
+// Memory is an in-process registry, registrar and discovery share the same instances.
+type Memory struct {
+	mu       sync.RWMutex
+	services map[string][]*ServiceInstance
+	watchers map[string]map[*memoryWatcher]struct{}
+}
+
+// NewMemory create an in-process registry.
+func NewMemory() *Memory {
+	return &Memory{
+		services: make(map[string][]*ServiceInstance),
+		watchers: make(map[string]map[*memoryWatcher]struct{}),
+	}
+}
+
+// Register the registration, an instance with the same ID is replaced.
+func (m *Memory) Register(ctx context.Context, service *ServiceInstance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := m.services[service.Name]
+	for i, s := range list {
+		if s.ID == service.ID {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	m.services[service.Name] = append(list, service)
+	m.notify(service.Name)
+	return nil
+}
+
+// Deregister the registration.
+func (m *Memory) Deregister(ctx context.Context, service *ServiceInstance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := m.services[service.Name]
+	for i, s := range list {
+		if s.ID == service.ID {
+			m.services[service.Name] = append(list[:i:i], list[i+1:]...)
+			m.notify(service.Name)
+			break
+		}
+	}
+	return nil
+}
+
+// GetService return the service instances according to the service name.
+func (m *Memory) GetService(ctx context.Context, serviceName string) ([]*ServiceInstance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*ServiceInstance(nil), m.services[serviceName]...), nil
+}
+
+// Watch creates a watcher according to the service name.
+func (m *Memory) Watch(ctx context.Context, serviceName string) (Watcher, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w := &memoryWatcher{
+		m:       m,
+		name:    serviceName,
+		changed: make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	// the first Next returns the current instances
+	w.changed <- struct{}{}
+	if m.watchers[serviceName] == nil {
+		m.watchers[serviceName] = make(map[*memoryWatcher]struct{})
+	}
+	m.watchers[serviceName][w] = struct{}{}
+	return w, nil
+}
+
+// notify wakes up the watchers of the service, the caller holds the lock.
+func (m *Memory) notify(serviceName string) {
+	for w := range m.watchers[serviceName] {
+		select {
+		case w.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+type memoryWatcher struct {
+	m       *Memory
+	name    string
+	changed chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func (w *memoryWatcher) Next() ([]*ServiceInstance, error) {
+	select {
+	case <-w.stopped:
+		return nil, ErrWatcherStopped
+	default:
+	}
+	select {
+	case <-w.stopped:
+		return nil, ErrWatcherStopped
+	case <-w.changed:
+		return w.m.GetService(context.Background(), w.name)
+	}
+}
+
+func (w *memoryWatcher) Stop() error {
+	w.once.Do(func() {
+		w.m.mu.Lock()
+		delete(w.m.watchers[w.name], w)
+		w.m.mu.Unlock()
+		close(w.stopped)
+	})
+	return nil
+}
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrWatcherStopped is returned by Watcher.Next once the watcher is stopped.
+var ErrWatcherStopped = errors.New("registry: watcher stopped")
+
+// Registrar is service registrar.
+type Registrar interface {
+	// Register the registration.
+	Register(ctx context.Context, service *ServiceInstance) error
+	// Deregister the registration.
+	Deregister(ctx context.Context, service *ServiceInstance) error
+}
+
+// Discovery is service discovery.
+type Discovery interface {
+	// GetService return the service instances in memory according to the service name.
+	GetService(ctx context.Context, serviceName string) ([]*ServiceInstance, error)
+	// Watch creates a watcher according to the service name.
+	Watch(ctx context.Context, serviceName string) (Watcher, error)
+}
+
+// Watcher is service watcher.
+type Watcher interface {
+	// Next returns the service instances on the first call and blocks until they change
+	// on the following calls, it returns ErrWatcherStopped once the watcher is stopped.
+	Next() ([]*ServiceInstance, error)
+	// Stop close the watcher.
+	Stop() error
+}
+
+// ServiceInstance is an instance of a service in a discovery system.
+type ServiceInstance struct {
+	// ID is the unique instance ID as registered.
+	ID string `json:"id"`
+	// Name is the service name as registered.
+	Name string `json:"name"`
+	// Version is the version of the compiled.
+	Version string `json:"version"`
+	// Metadata is the kv pair metadata associated with the service instance.
+	Metadata map[string]string `json:"metadata"`
+	// Endpoints are endpoint addresses of the service instance,
+	// e.g. grpc://127.0.0.1:9000, http://127.0.0.1:8000
+	Endpoints []string `json:"endpoints"`
+}
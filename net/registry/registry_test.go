@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry interface {
+			Registrar
+			Discovery
+		}
+	}{
+		{
+			name:     "memory",
+			registry: NewMemory(),
+		},
+		{
+			name:     "file",
+			registry: NewFile(filepath.Join(t.TempDir(), "registry.json"), WithWatchInterval(10*time.Millisecond)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			w, err := tt.registry.Watch(ctx, "user-service")
+			if err != nil {
+				t.Fatalf("Watch() error = %v", err)
+			}
+			if got, err := w.Next(); err != nil || len(got) != 0 {
+				t.Fatalf("Next() = %v, %v, want no instance", got, err)
+			}
+			instance := &ServiceInstance{
+				ID:        "1",
+				Name:      "user-service",
+				Version:   "v0.0.1",
+				Endpoints: []string{"grpc://127.0.0.1:9191"},
+			}
+			if err := tt.registry.Register(ctx, instance); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			got, err := w.Next()
+			if err != nil || !reflect.DeepEqual(got, []*ServiceInstance{instance}) {
+				t.Fatalf("Next() = %v, %v, want %v", got, err, instance)
+			}
+			got, err = tt.registry.GetService(ctx, "user-service")
+			if err != nil || !reflect.DeepEqual(got, []*ServiceInstance{instance}) {
+				t.Fatalf("GetService() = %v, %v, want %v", got, err, instance)
+			}
+			if err := tt.registry.Deregister(ctx, instance); err != nil {
+				t.Fatalf("Deregister() error = %v", err)
+			}
+			if got, err := w.Next(); err != nil || len(got) != 0 {
+				t.Fatalf("Next() = %v, %v, want no instance", got, err)
+			}
+			if err := w.Stop(); err != nil {
+				t.Fatalf("Stop() error = %v", err)
+			}
+			if _, err := w.Next(); err != ErrWatcherStopped {
+				t.Errorf("Next() error = %v, want %v", err, ErrWatcherStopped)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// AdminServiceName is the name of the admin service.
+	AdminServiceName = "admin.Admin"
+	// AdminTokenKey is the metadata key carrying the admin token besides authorization: Bearer.
+	AdminTokenKey = "x-admin-token"
+
+	adminGetLogLevelMethod = "/" + AdminServiceName + "/GetLogLevel"
+	adminSetLogLevelMethod = "/" + AdminServiceName + "/SetLogLevel"
+)
+
+// adminService changes the log level at runtime, requests and responses are structs:
+// GetLogLevel {name} -> {name, level, revert_at}
+// SetLogLevel {name, level, ttl} -> {name, level, revert_at}
+type adminService struct {
+	token string
+}
+
+// adminServiceDesc is written by hand, the messages are well known structs.
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: AdminServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLogLevel",
+			Handler:    adminHandler(adminGetLogLevelMethod, (*adminService).getLogLevel),
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    adminHandler(adminSetLogLevelMethod, (*adminService).setLogLevel),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin.proto",
+}
+
+func registerAdmin(s *grpc.Server, token string) {
+	s.RegisterService(&adminServiceDesc, &adminService{token: token})
+}
+
+func adminHandler(method string, fn func(*adminService, context.Context, *structpb.Struct) (*structpb.Struct, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(structpb.Struct)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			a := srv.(*adminService)
+			if err := a.auth(ctx); err != nil {
+				return nil, err
+			}
+			return fn(a, ctx, req.(*structpb.Struct))
+		}
+		if interceptor == nil {
+			return handler(ctx, in)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, handler)
+	}
+}
+
+// auth rejects requests without the admin token.
+func (a *adminService) auth(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var got string
+	if v := md.Get(AdminTokenKey); len(v) > 0 {
+		got = v[0]
+	} else if v := md.Get("authorization"); len(v) > 0 {
+		got = strings.TrimPrefix(v[0], "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid admin token")
+	}
+	return nil
+}
+
+func (a *adminService) getLogLevel(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	name := in.GetFields()["name"].GetStringValue()
+	level, revertAt, err := logger.LevelOf(name)
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	return logLevelStruct(name, level, revertAt)
+}
+
+func (a *adminService) setLogLevel(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	fields := in.GetFields()
+	name := fields["name"].GetStringValue()
+	level, err := logger.ParseLevel(fields["level"].GetStringValue())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var ttl time.Duration
+	if s := fields["ttl"].GetStringValue(); s != "" {
+		if ttl, err = time.ParseDuration(s); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	revertAt, err := logger.SetLevelFor(name, level, ttl)
+	if err != nil {
+		return nil, adminStatus(err)
+	}
+	logger.Warnf("[gRPC] admin changed logger %q level to %s ttl %s", name, level, ttl)
+	return logLevelStruct(name, level, revertAt)
+}
+
+func adminStatus(err error) error {
+	if errors.Is(err, logger.ErrLoggerNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func logLevelStruct(name string, level logger.Level, revertAt time.Time) (*structpb.Struct, error) {
+	m := map[string]interface{}{"name": name, "level": level.String()}
+	if !revertAt.IsZero() {
+		m["revert_at"] = revertAt.Format(time.RFC3339)
+	}
+	return structpb.NewStruct(m)
+}
+
+// AdminClient calls the admin service of a server, the token is sent with every call.
+type AdminClient struct {
+	cc    grpc.ClientConnInterface
+	token string
+}
+
+// NewAdminClient create an admin client.
+func NewAdminClient(cc grpc.ClientConnInterface, token string) *AdminClient {
+	return &AdminClient{cc: cc, token: token}
+}
+
+// GetLogLevel returns the level of the named logger, the empty name is the root logger.
+func (c *AdminClient) GetLogLevel(ctx context.Context, name string) (*structpb.Struct, error) {
+	in, err := structpb.NewStruct(map[string]interface{}{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	return c.invoke(ctx, adminGetLogLevelMethod, in)
+}
+
+// SetLogLevel changes the level of the named logger, a positive ttl reverts it once exceeded.
+func (c *AdminClient) SetLogLevel(ctx context.Context, name, level string, ttl time.Duration) (*structpb.Struct, error) {
+	m := map[string]interface{}{"name": name, "level": level}
+	if ttl > 0 {
+		m["ttl"] = ttl.String()
+	}
+	in, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil, err
+	}
+	return c.invoke(ctx, adminSetLogLevelMethod, in)
+}
+
+func (c *AdminClient) invoke(ctx context.Context, method string, in *structpb.Struct) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	ctx = metadata.AppendToOutgoingContext(ctx, AdminTokenKey, c.token)
+	if err := c.cc.Invoke(ctx, method, in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -31,6 +31,14 @@ func Dial(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn, error) {
 		options.unaryInterceptors = append(options.unaryInterceptors, otelgrpc.UnaryClientInterceptor())
 		options.streamInterceptors = append(options.streamInterceptors, otelgrpc.StreamClientInterceptor())
 	}
+	// 最外层的拦截器将服务端返回的错误解码为 errors.Error, 然后是熔断、超时和重试, 其他拦截器对每次重试都生效
+	outer := []grpc.UnaryClientInterceptor{ErrorUnaryClientInterceptor()}
+	if options.breakers != nil {
+		outer = append(outer, options.breakers.unaryInterceptor())
+	}
+	outer = append(outer, options.calls.unaryInterceptor())
+	options.unaryInterceptors = append(outer, options.unaryInterceptors...)
+	options.streamInterceptors = append([]grpc.StreamClientInterceptor{ErrorStreamClientInterceptor()}, options.streamInterceptors...)
 	grpcOpts := []grpc.DialOption{
 		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": %q}`, roundrobin.Name)),
 		grpc.WithChainUnaryInterceptor(options.unaryInterceptors...),
@@ -47,8 +55,14 @@ func Dial(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn, error) {
 		}
 		grpcOpts = append(grpcOpts, list...)
 	}
-	if options.insecure {
-		// RPC如果开启了TLS/SSL
+	if options.tls.enabled() {
+		// RPC开启了TLS/SSL, 证书文件修改后自动重新加载
+		creds, err := options.tls.credentials(false)
+		if err != nil {
+			return nil, err
+		}
+		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(creds))
+	} else if options.insecure {
 		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(grpcInsecure.NewCredentials()))
 	}
 	// RPC调用时候记录链路追踪指标
@@ -56,11 +70,13 @@ func Dial(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn, error) {
 		list := []grpc.DialOption{
 			// 将Jaeger 追踪器（Tracer）注入一元RPC调用拦截器
 			grpc.WithChainUnaryInterceptor(ClientInterceptor(options.tracer)),
-			grpc.WithChainUnaryInterceptor(ReleaseInterceptor()),
-			grpc.WithChainUnaryInterceptor(ReleaseInterceptorNew()),
 		}
 		grpcOpts = append(grpcOpts, list...)
 	}
+	// 通过服务发现解析 discovery:///<service name>
+	if options.discovery != nil {
+		grpcOpts = append(grpcOpts, grpc.WithResolvers(newDiscoveryBuilder(options.discovery)))
+	}
 	if len(options.grpcOpts) > 0 {
 		grpcOpts = append(grpcOpts, options.grpcOpts...)
 	}
@@ -89,7 +105,6 @@ func ClientInterceptor(tracer opentracing.Tracer) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 		// 实现一个一元调用拦截器
 		// 从上下文之中获取父跨度标识
-		fmt.Println("ClientInterceptor")
 		parentSpan := ctx.Value(enum.TraceSpanName)
 		if parentSpan != nil {
 			parentSpanContext := parentSpan.(opentracing.SpanContext)
@@ -104,29 +119,3 @@ func ClientInterceptor(tracer opentracing.Tracer) grpc.UnaryClientInterceptor {
 		return invoker(ctx, method, req, reply, cc, opts...)
 	}
 }
-
-// ReleaseInterceptor RPC调用完成释放连接
-func ReleaseInterceptor() grpc.UnaryClientInterceptor {
-	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		// 实现一个一元调用拦截器
-		fmt.Println("GRPC请求开始...")
-		defer func() {
-			fmt.Println("GRPC的请求完成通知gRPC连接池释放连接...")
-		}()
-		// invoker真正的发起RPC调用,并且调用完成之后进入defer
-		return invoker(ctx, method, req, reply, cc, opts...)
-	}
-}
-
-// ReleaseInterceptorNew RPC调用完成释放连接
-func ReleaseInterceptorNew() grpc.UnaryClientInterceptor {
-	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		// 实现一个一元调用拦截器
-		fmt.Println("GRPC请求开始New...")
-		defer func() {
-			fmt.Println("GRPC的请求完成通知gRPC连接池释放连接New...")
-		}()
-		// invoker真正的发起RPC调用,并且调用完成之后进入defer
-		return invoker(ctx, method, req, reply, cc, opts...)
-	}
-}
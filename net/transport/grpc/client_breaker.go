@@ -0,0 +1,288 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets the calls through and counts their failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects the calls with errors.ErrBreakerOpen until OpenTimeout.
+	BreakerOpen
+	// BreakerHalfOpen lets HalfOpenRequests probe calls through to close or open again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+const (
+	BreakerTargetLabel = "target"
+	BreakerMethodLabel = "method"
+)
+
+// ClientBreakerStateGauge 1.熔断器状态, 0关闭 1打开 2半开
+var ClientBreakerStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "grpc_client_breaker_state",
+	Help: "gRPC客户端熔断器状态(0关闭 1打开 2半开)",
+}, []string{BreakerTargetLabel, BreakerMethodLabel})
+
+// ClientBreakerRejectedCounter 2.熔断器打开时拒绝的请求数
+var ClientBreakerRejectedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_client_breaker_rejected_total",
+	Help: "gRPC客户端熔断器拒绝的请求数",
+}, []string{BreakerTargetLabel, BreakerMethodLabel})
+
+// BreakerConfig opens the breaker of a target and method once the rate of the failed or the
+// slow calls in the rolling window reaches its threshold.
+type BreakerConfig struct {
+	// Window is the rolling window of the counted calls, split in Buckets.
+	Window  time.Duration
+	Buckets int
+	// MinRequests is the number of calls in the window before the rates are checked.
+	MinRequests int
+	// ErrorRate opens the breaker, between 0 and 1.
+	ErrorRate float64
+	// SlowCallDuration is the latency of a slow call, 0 does not count the slow calls.
+	SlowCallDuration time.Duration
+	// SlowCallRate opens the breaker, between 0 and 1.
+	SlowCallRate float64
+	// OpenTimeout is the time before letting probe calls through.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of successful probe calls closing the breaker.
+	HalfOpenRequests int
+	// FailureCodes are the codes of the failed calls, the ones of an unhealthy server
+	// if not set, e.g. an InvalidArgument is not a failure.
+	FailureCodes []codes.Code
+}
+
+// DefaultBreakerConfig opens after 50% of failed or slow (1s) calls among at least 20 calls
+// in 10s, and probes with 3 calls after 5s.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		Window:           10 * time.Second,
+		Buckets:          10,
+		MinRequests:      20,
+		ErrorRate:        0.5,
+		SlowCallDuration: time.Second,
+		SlowCallRate:     0.5,
+		OpenTimeout:      5 * time.Second,
+		HalfOpenRequests: 3,
+	}
+}
+
+var defaultFailureCodes = []codes.Code{codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unavailable}
+
+func (c BreakerConfig) failure(err error) bool {
+	if err == nil {
+		return false
+	}
+	failureCodes := c.FailureCodes
+	if len(failureCodes) == 0 {
+		failureCodes = defaultFailureCodes
+	}
+	code := status.Code(err)
+	for _, failureCode := range failureCodes {
+		if code == failureCode {
+			return true
+		}
+	}
+	return false
+}
+
+type breakerBucket struct {
+	start    time.Time
+	total    int
+	failures int
+	slow     int
+}
+
+// breaker is the circuit breaker of a target and method.
+type breaker struct {
+	config BreakerConfig
+	target string
+	method string
+
+	mu       sync.Mutex
+	state    BreakerState
+	buckets  []breakerBucket
+	openedAt time.Time
+	probes   int
+	passed   int
+}
+
+func newBreaker(config BreakerConfig, target, method string) *breaker {
+	b := &breaker{config: config, target: target, method: method, buckets: make([]breakerBucket, config.Buckets)}
+	ClientBreakerStateGauge.WithLabelValues(target, method).Set(float64(BreakerClosed))
+	return b
+}
+
+// allow reports whether a call is let through, a call let through must be recorded by done.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.config.OpenTimeout {
+			return false
+		}
+		b.setState(BreakerHalfOpen, now)
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.config.HalfOpenRequests {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// done records the result of a call let through.
+func (b *breaker) done(now time.Time, elapsed time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	failed := b.config.failure(err)
+	slow := b.config.SlowCallDuration > 0 && elapsed >= b.config.SlowCallDuration
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed || slow {
+			b.setState(BreakerOpen, now)
+			return
+		}
+		if b.passed++; b.passed >= b.config.HalfOpenRequests {
+			b.setState(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		bucket := b.bucket(now)
+		bucket.total++
+		if failed {
+			bucket.failures++
+		}
+		if slow {
+			bucket.slow++
+		}
+		if b.tripped(now) {
+			b.setState(BreakerOpen, now)
+		}
+	}
+}
+
+// bucket returns the bucket of now, resetting it when it is the one of an older window.
+func (b *breaker) bucket(now time.Time) *breakerBucket {
+	size := b.config.Window / time.Duration(len(b.buckets))
+	start := now.Truncate(size)
+	bucket := &b.buckets[int(start.UnixNano()/int64(size))%len(b.buckets)]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+func (b *breaker) tripped(now time.Time) bool {
+	var total, failures, slow int
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.config.Window {
+			total += bucket.total
+			failures += bucket.failures
+			slow += bucket.slow
+		}
+	}
+	if total == 0 || total < b.config.MinRequests {
+		return false
+	}
+	if float64(failures)/float64(total) >= b.config.ErrorRate {
+		return true
+	}
+	return b.config.SlowCallDuration > 0 && float64(slow)/float64(total) >= b.config.SlowCallRate
+}
+
+func (b *breaker) setState(state BreakerState, now time.Time) {
+	b.state = state
+	b.probes, b.passed = 0, 0
+	switch state {
+	case BreakerOpen:
+		b.openedAt = now
+	case BreakerClosed:
+		b.buckets = make([]breakerBucket, len(b.buckets))
+	}
+	ClientBreakerStateGauge.WithLabelValues(b.target, b.method).Set(float64(state))
+}
+
+// breakers is the circuit breakers of a client by target and method.
+type breakers struct {
+	config BreakerConfig
+	now    func() time.Time
+	mu     sync.Mutex
+	m      map[string]*breaker
+}
+
+func newBreakers(config BreakerConfig) *breakers {
+	defaults := DefaultBreakerConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.Buckets <= 0 {
+		config.Buckets = defaults.Buckets
+	}
+	if config.ErrorRate <= 0 {
+		config.ErrorRate = defaults.ErrorRate
+	}
+	if config.SlowCallRate <= 0 {
+		config.SlowCallRate = defaults.SlowCallRate
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaults.OpenTimeout
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	return &breakers{config: config, now: time.Now, m: make(map[string]*breaker)}
+}
+
+func (b *breakers) get(target, method string) *breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := target + method
+	br, ok := b.m[key]
+	if !ok {
+		br = newBreaker(b.config, target, method)
+		b.m[key] = br
+	}
+	return br
+}
+
+// unaryInterceptor rejects the calls of an open breaker with errors.ErrBreakerOpen, a
+// retried call is counted once with the result of its last attempt.
+func (b *breakers) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		br := b.get(cc.Target(), method)
+		begin := b.now()
+		if !br.allow(begin) {
+			ClientBreakerRejectedCounter.WithLabelValues(br.target, method).Inc()
+			return errors.ErrBreakerOpen.WithMessage("%s circuit breaker of %s is open", method, br.target)
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		now := b.now()
+		br.done(now, now.Sub(begin), err)
+		return err
+	}
+}
@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	"context"
+	stdErrors "errors"
+	"testing"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	config := BreakerConfig{
+		Window:           time.Second,
+		Buckets:          10,
+		MinRequests:      4,
+		ErrorRate:        0.5,
+		SlowCallDuration: 100 * time.Millisecond,
+		SlowCallRate:     0.5,
+		OpenTimeout:      time.Second,
+		HalfOpenRequests: 2,
+	}
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	invalid := status.Error(codes.InvalidArgument, "invalid")
+	now := time.Now()
+	b := newBreakers(config).get("target", "/test.Test/Method")
+	call := func(elapsed time.Duration, err error) bool {
+		if !b.allow(now) {
+			return false
+		}
+		b.done(now, elapsed, err)
+		return true
+	}
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		elapsed   time.Duration
+		err       error
+		wantAllow bool
+		wantState BreakerState
+	}{
+		{"success", 0, time.Millisecond, nil, true, BreakerClosed},
+		{"client error is not a failure", 0, time.Millisecond, invalid, true, BreakerClosed},
+		{"failure", 0, time.Millisecond, unavailable, true, BreakerClosed},
+		{"error rate reached", 0, time.Millisecond, unavailable, true, BreakerOpen},
+		{"open rejects", 500 * time.Millisecond, 0, nil, false, BreakerOpen},
+		{"half-open probe", time.Second, time.Millisecond, nil, true, BreakerHalfOpen},
+		{"slow probe opens", 0, time.Second, nil, true, BreakerOpen},
+		{"half-open probe again", time.Second, time.Millisecond, nil, true, BreakerHalfOpen},
+		{"probes close", 0, time.Millisecond, nil, true, BreakerClosed},
+		{"closing resets the window", 0, time.Millisecond, unavailable, true, BreakerClosed},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if allowed := call(step.elapsed, step.err); allowed != step.wantAllow {
+			t.Fatalf("%s: allowed = %v, want %v", step.name, allowed, step.wantAllow)
+		}
+		if b.state != step.wantState {
+			t.Fatalf("%s: state = %v, want %v", step.name, b.state, step.wantState)
+		}
+	}
+	// the slow calls open the breaker too
+	now = now.Add(2 * time.Second)
+	for i := 0; i < 4; i++ {
+		call(200*time.Millisecond, nil)
+	}
+	if b.state != BreakerOpen {
+		t.Errorf("state = %v after slow calls, want %v", b.state, BreakerOpen)
+	}
+}
+
+func TestDial_CircuitBreaker(t *testing.T) {
+	config := DefaultBreakerConfig()
+	config.MinRequests = 2
+	conn, attempts := dialHandler(t, failing(100, codes.Unavailable), WithCircuitBreaker(config))
+	for i := 0; i < 2; i++ {
+		if _, err := invoke(conn); status.Code(err) != codes.Unavailable || stdErrors.Is(err, errors.ErrBreakerOpen) {
+			t.Fatalf("Invoke() error = %v, want the error of the server", err)
+		}
+	}
+	_, err := invoke(conn)
+	if !stdErrors.Is(err, errors.ErrBreakerOpen) {
+		t.Fatalf("Invoke() error = %v, want %v", err, errors.ErrBreakerOpen)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2, the open breaker does not call the server", got)
+	}
+	if got := status.Code(err); got != codes.Unavailable {
+		t.Errorf("status.Code() = %v, want %v", got, codes.Unavailable)
+	}
+}
+
+func TestDial_CircuitBreakerShared(t *testing.T) {
+	config := DefaultBreakerConfig()
+	config.MinRequests = 2
+	breaker := WithCircuitBreaker(config)
+	conn, attempts := dialHandler(t, failing(100, codes.Unavailable), breaker)
+	// a second connection dialed with the same option, like the connections of a pool
+	other, err := Dial(context.Background(), WithEndpoint(conn.Target()), WithInSecure(true), breaker)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer other.Close()
+	for i := 0; i < 2; i++ {
+		_, _ = invoke(conn)
+	}
+	if _, err := invoke(other); !stdErrors.Is(err, errors.ErrBreakerOpen) {
+		t.Fatalf("Invoke() of the other connection error = %v, want %v", err, errors.ErrBreakerOpen)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2, the breaker is shared by the connections", got)
+	}
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"google.golang.org/grpc"
+)
+
+// ErrorUnaryClientInterceptor decodes the status errors carrying an errdetails.ErrorInfo back
+// into the errors.Error returned by the server handler, the other errors are returned as is.
+func ErrorUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return decodeError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// ErrorStreamClientInterceptor decodes the errors of the stream like ErrorUnaryClientInterceptor.
+func ErrorStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, decodeError(err)
+		}
+		return &errorClientStream{ClientStream: stream}, nil
+	}
+}
+
+type errorClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorClientStream) SendMsg(m interface{}) error {
+	return decodeError(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorClientStream) RecvMsg(m interface{}) error {
+	return decodeError(s.ClientStream.RecvMsg(m))
+}
+
+func decodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.Decode(err)
+}
@@ -1,7 +1,11 @@
 package grpc
 
 import (
+	"crypto/tls"
+	"time"
+
 	"github.com/opentracing/opentracing-go"
+	"github.com/weiqiangxu/micro_project/net/registry"
 	"google.golang.org/grpc"
 )
 
@@ -16,6 +20,10 @@ type clientOptions struct {
 	prometheus         bool
 	tracer             opentracing.Tracer
 	tracerInterceptor  bool
+	discovery          registry.Discovery
+	tls                tlsFiles
+	calls              callPolicies
+	breakers           *breakers
 }
 
 // ClientOption is gRPC client option.
@@ -28,6 +36,13 @@ func WithEndpoint(endpoint string) ClientOption {
 	}
 }
 
+// WithDiscovery with service discovery, the endpoint is discovery:///<service name>.
+func WithDiscovery(d registry.Discovery) ClientOption {
+	return func(c *clientOptions) {
+		c.discovery = d
+	}
+}
+
 // WithUnaryTraceInterceptor with client endpoint.
 func WithUnaryTraceInterceptor(tracer opentracing.Tracer) ClientOption {
 	return func(c *clientOptions) {
@@ -71,3 +86,102 @@ func WithInSecure(insecure bool) ClientOption {
 		c.insecure = insecure
 	}
 }
+
+// WithTLSConfig dials with TLS, the files of WithCA and WithClientCert are added to config.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(c *clientOptions) {
+		c.tls.config = config
+	}
+}
+
+// WithCA dials with TLS verifying the server certificate with the CA bundle of caFile,
+// the bundle is reloaded once modified on disk.
+func WithCA(caFile string) ClientOption {
+	return func(c *clientOptions) {
+		c.tls.caFile = caFile
+	}
+}
+
+// WithClientCert dials with TLS presenting the client certificate for mutual TLS,
+// the files are reloaded once modified on disk.
+func WithClientCert(certFile, keyFile string) ClientOption {
+	return func(c *clientOptions) {
+		c.tls.certFile = certFile
+		c.tls.keyFile = keyFile
+	}
+}
+
+// WithServerName verifies the server certificate against name instead of the endpoint host.
+func WithServerName(name string) ClientOption {
+	return func(c *clientOptions) {
+		c.tls.serverName = name
+	}
+}
+
+// WithCertReloadInterval is the minimum interval between two checks of the certificate files,
+// DefaultCertReloadInterval if not set.
+func WithCertReloadInterval(interval time.Duration) ClientOption {
+	return func(c *clientOptions) {
+		c.tls.reloadInterval = interval
+	}
+}
+
+// WithTimeout is the default deadline of the unary calls without a deadline, it bounds
+// all the attempts of a retried call. 0 disables it.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *clientOptions) {
+		c.calls.timeout = timeout
+	}
+}
+
+// WithMethodTimeout overrides the default deadline of a method, e.g. /user.Login/GetUserInfo.
+func WithMethodTimeout(method string, timeout time.Duration) ClientOption {
+	return func(c *clientOptions) {
+		if c.calls.methodTimeouts == nil {
+			c.calls.methodTimeouts = make(map[string]time.Duration)
+		}
+		c.calls.methodTimeouts[method] = timeout
+	}
+}
+
+// WithRetry retries the unary calls of method with policy, the method * applies to every
+// method without its own policy.
+func WithRetry(method string, policy RetryPolicy) ClientOption {
+	return func(c *clientOptions) {
+		if c.calls.retries == nil {
+			c.calls.retries = make(map[string]RetryPolicy)
+		}
+		c.calls.retries[method] = policy
+	}
+}
+
+// WithHedging hedges the unary calls of an idempotent method with policy, a hedged method
+// is not retried.
+func WithHedging(method string, policy HedgingPolicy) ClientOption {
+	return func(c *clientOptions) {
+		if c.calls.hedging == nil {
+			c.calls.hedging = make(map[string]HedgingPolicy)
+		}
+		c.calls.hedging[method] = policy
+	}
+}
+
+// WithRetryBudget throttles the retries and the hedged attempts of the client to prevent
+// retry storms when the server is unavailable. The budget is shared by the connections
+// dialed with the same option, e.g. the connections of a grpc_pool.
+func WithRetryBudget(budget RetryBudget) ClientOption {
+	b := newRetryBudget(budget)
+	return func(c *clientOptions) {
+		c.calls.budget = b
+	}
+}
+
+// WithCircuitBreaker adds a circuit breaker per target and method, the calls of an open
+// breaker fail at once with errors.ErrBreakerOpen. The breakers are shared by the connections
+// dialed with the same option, e.g. the connections of a grpc_pool.
+func WithCircuitBreaker(config BreakerConfig) ClientOption {
+	b := newBreakers(config)
+	return func(c *clientOptions) {
+		c.breakers = b
+	}
+}
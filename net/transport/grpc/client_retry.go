@@ -0,0 +1,289 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// RetryPolicy retries the failed calls of a method with an exponential backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one.
+	MaxAttempts int
+	// RetryableCodes are the codes retried, Unavailable if not set.
+	RetryableCodes []codes.Code
+	// InitialBackoff is the backoff before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff bounds the backoff.
+	MaxBackoff time.Duration
+	// BackoffMultiplier multiplies the backoff after every retry.
+	BackoffMultiplier float64
+	// Jitter randomizes the backoff by +-Jitter, between 0 and 1.
+	Jitter float64
+}
+
+// DefaultRetryPolicy retries Unavailable twice after 50ms and 100ms, +-20%.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       3,
+		RetryableCodes:    []codes.Code{codes.Unavailable},
+		InitialBackoff:    50 * time.Millisecond,
+		MaxBackoff:        time.Second,
+		BackoffMultiplier: 2,
+		Jitter:            0.2,
+	}
+}
+
+// backoff returns the backoff before the retry n, starting at 1.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.BackoffMultiplier, float64(n-1))
+	if p.MaxBackoff > 0 {
+		backoff = math.Min(backoff, float64(p.MaxBackoff))
+	}
+	backoff *= 1 + p.Jitter*(2*rand.Float64()-1)
+	return time.Duration(backoff)
+}
+
+func (p RetryPolicy) retryable(code codes.Code) bool {
+	if len(p.RetryableCodes) == 0 {
+		return code == codes.Unavailable
+	}
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HedgingPolicy sends the same call again when no response arrived after HedgingDelay, the
+// first response wins and the other attempts are canceled. Only idempotent methods, e.g.
+// the reads, may be hedged.
+type HedgingPolicy struct {
+	// MaxAttempts is the number of attempts including the first one.
+	MaxAttempts int
+	// HedgingDelay is the delay before sending the next attempt.
+	HedgingDelay time.Duration
+	// NonFatalCodes are the codes of the failed attempts waiting for the others, the
+	// other codes fail the call at once. Unavailable if not set.
+	NonFatalCodes []codes.Code
+}
+
+func (p HedgingPolicy) nonFatal(code codes.Code) bool {
+	return RetryPolicy{RetryableCodes: p.NonFatalCodes}.retryable(code)
+}
+
+// RetryBudget throttles the retries and the hedged attempts of a client with the token
+// algorithm of the gRPC retry throttling: a failure takes a token, a success gives back
+// TokenRatio tokens, and no retry is sent while the tokens are below MaxTokens/2.
+type RetryBudget struct {
+	MaxTokens  float64
+	TokenRatio float64
+}
+
+// DefaultRetryBudget stops retrying once about 1 call in 10 fails.
+func DefaultRetryBudget() RetryBudget {
+	return RetryBudget{MaxTokens: 10, TokenRatio: 0.1}
+}
+
+type retryBudget struct {
+	budget RetryBudget
+	mu     sync.Mutex
+	tokens float64
+}
+
+func newRetryBudget(budget RetryBudget) *retryBudget {
+	return &retryBudget{budget: budget, tokens: budget.MaxTokens}
+}
+
+func (b *retryBudget) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens > b.budget.MaxTokens/2
+}
+
+func (b *retryBudget) record(success bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.tokens = math.Min(b.budget.MaxTokens, b.tokens+b.budget.TokenRatio)
+	} else {
+		b.tokens = math.Max(0, b.tokens-1)
+	}
+}
+
+// callPolicies is the deadline, retry and hedging policies of the methods of a client,
+// the method * applies to every method without its own policy.
+type callPolicies struct {
+	timeout        time.Duration
+	methodTimeouts map[string]time.Duration
+	retries        map[string]RetryPolicy
+	hedging        map[string]HedgingPolicy
+	budget         *retryBudget
+}
+
+func (p *callPolicies) retry(method string) (RetryPolicy, bool) {
+	if policy, ok := p.retries[method]; ok {
+		return policy, policy.MaxAttempts > 1
+	}
+	policy, ok := p.retries["*"]
+	return policy, ok && policy.MaxAttempts > 1
+}
+
+func (p *callPolicies) hedge(method string) (HedgingPolicy, bool) {
+	policy, ok := p.hedging[method]
+	return policy, ok && policy.MaxAttempts > 1
+}
+
+// unaryInterceptor applies the default deadline, then hedges or retries the unary calls
+// with their policies, the streams are not retried.
+func (p *callPolicies) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		timeout, ok := p.methodTimeouts[method]
+		if !ok {
+			timeout = p.timeout
+		}
+		ctx, cancel := withDefaultTimeout(ctx, timeout)
+		defer cancel()
+		if policy, ok := p.hedge(method); ok {
+			if m, ok := reply.(proto.Message); ok {
+				return p.invokeHedged(ctx, policy, method, req, m, cc, invoker, opts)
+			}
+		}
+		policy, ok := p.retry(method)
+		if !ok {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			p.record(policy, err)
+			return err
+		}
+		return p.invokeRetried(ctx, policy, method, req, reply, cc, invoker, opts)
+	}
+}
+
+func (p *callPolicies) invokeRetried(ctx context.Context, policy RetryPolicy, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts []grpc.CallOption) error {
+	for attempt := 1; ; attempt++ {
+		var trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+		p.record(policy, err)
+		if err == nil || !policy.retryable(status.Code(err)) {
+			return err
+		}
+		if attempt >= policy.MaxAttempts || !p.budget.allow() {
+			return err
+		}
+		// 服务端限流时按服务端要求的时间之后重试
+		backoff := policy.backoff(attempt)
+		if delay := retryDelay(err, trailer); delay > backoff {
+			backoff = delay
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// record gives a token back for a success and takes one for a retryable failure, the
+// other failures do not tell the health of the server.
+func (p *callPolicies) record(policy RetryPolicy, err error) {
+	if err == nil {
+		p.budget.record(true)
+	} else if policy.retryable(status.Code(err)) {
+		p.budget.record(false)
+	}
+}
+
+type hedgeResult struct {
+	reply proto.Message
+	err   error
+}
+
+func (p *callPolicies) invokeHedged(ctx context.Context, policy HedgingPolicy, method string, req interface{}, reply proto.Message, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts []grpc.CallOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	// 返回时取消其他仍在进行的请求
+	defer cancel()
+	results := make(chan hedgeResult, policy.MaxAttempts)
+	send := func() {
+		attemptReply := proto.Clone(reply)
+		proto.Reset(attemptReply)
+		go func() {
+			err := invoker(ctx, method, req, attemptReply, cc, opts...)
+			results <- hedgeResult{reply: attemptReply, err: err}
+		}()
+	}
+	send()
+	sent, pending := 1, 1
+	timer := time.NewTimer(policy.HedgingDelay)
+	defer timer.Stop()
+	var err error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if sent < policy.MaxAttempts && p.budget.allow() {
+				send()
+				sent++
+				pending++
+				timer.Reset(policy.HedgingDelay)
+			}
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				p.budget.record(true)
+				proto.Reset(reply)
+				proto.Merge(reply, result.reply)
+				return nil
+			}
+			err = result.err
+			if !policy.nonFatal(status.Code(err)) {
+				return err
+			}
+			p.budget.record(false)
+			// 失败的请求不等待延迟, 立即发送下一次
+			if pending == 0 && sent < policy.MaxAttempts && p.budget.allow() {
+				send()
+				sent++
+				pending++
+				timer.Reset(policy.HedgingDelay)
+			}
+		}
+	}
+	return err
+}
+
+// retryDelay returns the delay asked by the server with an errdetails.RetryInfo or a
+// retry-after trailer, 0 if none.
+func retryDelay(err error, trailer metadata.MD) time.Duration {
+	if s, ok := status.FromError(err); ok {
+		for _, detail := range s.Details() {
+			if info, ok := detail.(*errdetails.RetryInfo); ok {
+				return info.GetRetryDelay().AsDuration()
+			}
+		}
+	}
+	if v := trailer.Get(RetryAfterKey); len(v) > 0 {
+		if seconds, err := strconv.Atoi(v[0]); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
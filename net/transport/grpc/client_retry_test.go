@@ -0,0 +1,150 @@
+package grpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// dialHandler dials a server answering every unary call with handle, called with the
+// number of the attempt starting at 1.
+func dialHandler(t *testing.T, handle func(ctx context.Context, attempt int) (string, error), opts ...ClientOption) (*grpc.ClientConn, *atomic.Int32) {
+	t.Helper()
+	attempts := new(atomic.Int32)
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		if err := stream.RecvMsg(new(emptypb.Empty)); err != nil {
+			return err
+		}
+		value, err := handle(stream.Context(), int(attempts.Add(1)))
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(wrapperspb.String(value))
+	}
+	srv := NewServer(Address("127.0.0.1:0"), Timeout(0), Options(grpc.UnknownServiceHandler(handler)))
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	t.Cleanup(func() { srv.Server.Stop() })
+	conn, err := Dial(context.Background(), append([]ClientOption{WithEndpoint(endpoint.Host), WithInSecure(true)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, attempts
+}
+
+func invoke(conn *grpc.ClientConn) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	reply := new(wrapperspb.StringValue)
+	err := conn.Invoke(ctx, "/test.Retry/Get", new(emptypb.Empty), reply)
+	return reply.GetValue(), err
+}
+
+// failing fails the first n attempts with code.
+func failing(n int, code codes.Code) func(context.Context, int) (string, error) {
+	return func(_ context.Context, attempt int) (string, error) {
+		if attempt <= n {
+			return "", status.Error(code, "failing")
+		}
+		return "ok", nil
+	}
+}
+
+func TestDial_Retry(t *testing.T) {
+	fast := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, BackoffMultiplier: 2, Jitter: 0.2}
+	tests := []struct {
+		name         string
+		handle       func(context.Context, int) (string, error)
+		opts         []ClientOption
+		wantCode     codes.Code
+		wantAttempts int32
+	}{
+		{"no retry policy", failing(1, codes.Unavailable), nil, codes.Unavailable, 1},
+		{"retried", failing(2, codes.Unavailable), []ClientOption{WithRetry("*", fast)}, codes.OK, 3},
+		{"max attempts", failing(5, codes.Unavailable), []ClientOption{WithRetry("*", fast)}, codes.Unavailable, 3},
+		{"not retryable", failing(1, codes.InvalidArgument), []ClientOption{WithRetry("*", fast)}, codes.InvalidArgument, 1},
+		{"method policy", failing(1, codes.Unavailable), []ClientOption{WithRetry("*", fast), WithRetry("/test.Retry/Get", RetryPolicy{MaxAttempts: 1})}, codes.Unavailable, 1},
+		{"budget exhausted", failing(5, codes.Unavailable), []ClientOption{WithRetry("*", fast), WithRetryBudget(RetryBudget{MaxTokens: 2, TokenRatio: 0.1})}, codes.Unavailable, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, attempts := dialHandler(t, tt.handle, tt.opts...)
+			if _, err := invoke(conn); status.Code(err) != tt.wantCode {
+				t.Errorf("Invoke() error = %v, want %v", err, tt.wantCode)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestDial_RetryAfter(t *testing.T) {
+	handle := func(_ context.Context, attempt int) (string, error) {
+		if attempt == 1 {
+			s, _ := status.New(codes.ResourceExhausted, "limited").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(200 * time.Millisecond)})
+			return "", s.Err()
+		}
+		return "ok", nil
+	}
+	policy := RetryPolicy{MaxAttempts: 2, RetryableCodes: []codes.Code{codes.ResourceExhausted}, InitialBackoff: time.Millisecond}
+	conn, _ := dialHandler(t, handle, WithRetry("*", policy))
+	begin := time.Now()
+	if _, err := invoke(conn); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed < 200*time.Millisecond {
+		t.Errorf("retried after %v, want the 200ms asked by the server", elapsed)
+	}
+}
+
+func TestDial_Hedging(t *testing.T) {
+	// the first attempt is slow, the hedged one answers at once
+	handle := func(ctx context.Context, attempt int) (string, error) {
+		if attempt == 1 {
+			<-ctx.Done()
+			return "", status.FromContextError(ctx.Err()).Err()
+		}
+		return "hedged", nil
+	}
+	conn, attempts := dialHandler(t, handle, WithHedging("/test.Retry/Get", HedgingPolicy{MaxAttempts: 2, HedgingDelay: 20 * time.Millisecond}))
+	begin := time.Now()
+	value, err := invoke(conn)
+	if err != nil || value != "hedged" {
+		t.Fatalf("Invoke() = %q, %v, want the hedged reply", value, err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Invoke() returned after %v, want the hedged attempt", elapsed)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestDial_Timeout(t *testing.T) {
+	wait := func(ctx context.Context, _ int) (string, error) {
+		<-ctx.Done()
+		return "", status.FromContextError(ctx.Err()).Err()
+	}
+	conn, _ := dialHandler(t, wait, WithTimeout(time.Minute), WithMethodTimeout("/test.Retry/Get", 50*time.Millisecond))
+	begin := time.Now()
+	if err := conn.Invoke(context.Background(), "/test.Retry/Get", new(emptypb.Empty), new(wrapperspb.StringValue)); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Invoke() error = %v, want %v", err, codes.DeadlineExceeded)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Invoke() returned after %v, want about 50ms", elapsed)
+	}
+}
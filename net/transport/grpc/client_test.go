@@ -2,9 +2,15 @@ package grpc
 
 import (
 	"context"
+	stdErrors "errors"
 	"testing"
+	"time"
 
+	"github.com/weiqiangxu/micro_project/common-config/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 func TestDial(t *testing.T) {
@@ -45,3 +51,25 @@ func TestDial(t *testing.T) {
 		})
 	}
 }
+
+func TestDial_DecodeError(t *testing.T) {
+	conn := dialTestService(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := conn.Invoke(ctx, "/test.Test/Error", new(emptypb.Empty), new(emptypb.Empty))
+	var e *errors.Error
+	if !stdErrors.As(err, &e) {
+		t.Fatalf("Invoke() error = %#v, want an errors.Error", err)
+	}
+	if !stdErrors.Is(err, errors.ErrInvalidParams) || e.Message != "uid is required" || e.Metadata["field"] != "uid" {
+		t.Errorf("Invoke() error = %v, want the invalid params error of the handler", err)
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("status.Code() = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+	// the errors without details are kept
+	err = conn.Invoke(ctx, "/test.Test/Unknown", new(emptypb.Empty), new(emptypb.Empty))
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Invoke() error = %v, want %v", err, codes.Unimplemented)
+	}
+}
@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+	"github.com/weiqiangxu/micro_project/net/registry"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// SchemeOfDiscovery is the target scheme resolved by the discovery, e.g. discovery:///user-service
+	SchemeOfDiscovery = "discovery"
+	// discoveryRetryInterval is the interval the resolver waits after a watch error
+	discoveryRetryInterval = time.Second
+)
+
+var _ resolver.Builder = (*discoveryBuilder)(nil)
+
+// discoveryBuilder builds resolvers watching the service of the target in the discovery
+type discoveryBuilder struct {
+	discovery registry.Discovery
+}
+
+func newDiscoveryBuilder(d registry.Discovery) *discoveryBuilder {
+	return &discoveryBuilder{discovery: d}
+}
+
+func (b *discoveryBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	name := strings.TrimPrefix(target.URL.Path, "/")
+	if name == "" {
+		name = target.URL.Host
+	}
+	w, err := b.discovery.Watch(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	r := &discoveryResolver{name: name, watcher: w, cc: cc}
+	go r.watch()
+	return r, nil
+}
+
+func (b *discoveryBuilder) Scheme() string {
+	return SchemeOfDiscovery
+}
+
+// discoveryResolver pushes the grpc endpoints of the watched instances to the client conn
+type discoveryResolver struct {
+	name    string
+	watcher registry.Watcher
+	cc      resolver.ClientConn
+}
+
+func (r *discoveryResolver) watch() {
+	for {
+		instances, err := r.watcher.Next()
+		if errors.Is(err, registry.ErrWatcherStopped) {
+			return
+		}
+		if err != nil {
+			logger.Errorf("[resolver] watch %s catch err=%v", r.name, err)
+			r.cc.ReportError(err)
+			time.Sleep(discoveryRetryInterval)
+			continue
+		}
+		r.update(instances)
+	}
+}
+
+func (r *discoveryResolver) update(instances []*registry.ServiceInstance) {
+	addrs := make([]resolver.Address, 0, len(instances))
+	for _, ins := range instances {
+		for _, e := range ins.Endpoints {
+			u, err := url.Parse(e)
+			if err != nil || u.Scheme != SchemeOfGrpc {
+				continue
+			}
+			addrs = append(addrs, resolver.Address{Addr: u.Host})
+		}
+	}
+	if len(addrs) == 0 {
+		logger.Warnf("[resolver] %s has no grpc endpoint, keep the current addresses", r.name)
+		return
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		logger.Errorf("[resolver] update %s state catch err=%v", r.name, err)
+	}
+}
+
+func (r *discoveryResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *discoveryResolver) Close() {
+	if err := r.watcher.Stop(); err != nil {
+		logger.Errorf("[resolver] stop %s watcher catch err=%v", r.name, err)
+	}
+}
@@ -19,7 +19,11 @@ import (
 	"google.golang.org/grpc/reflection"
 )
 
-var _ transport.Server = (*Server)(nil)
+var (
+	_ transport.Server         = (*Server)(nil)
+	_ transport.Endpointer     = (*Server)(nil)
+	_ transport.ListenerCloser = (*Server)(nil)
+)
 
 const (
 	DefaultNetProtocol = "tcp"
@@ -38,41 +42,77 @@ type Server struct {
 	address           string
 	endpoint          *url.URL
 	timeout           time.Duration
+	methodTimeouts    map[string]time.Duration
+	keepalive         keepalive.ServerParameters
+	enforcement       keepalive.EnforcementPolicy
+	limiter           *limiter
 	unaryInterceptor  []grpc.UnaryServerInterceptor
 	streamInterceptor []grpc.StreamServerInterceptor
 	grpcOpts          []grpc.ServerOption
 	health            *health.Server
 	tracing           bool
 	recovery          bool
+	adminToken        string
+	tls               tlsFiles
 }
 
 func NewServer(opts ...ServerOption) *Server {
-	server := &Server{network: DefaultNetProtocol, address: DefaultNetAddress, timeout: 1 * time.Second, health: health.NewServer()}
+	server := &Server{
+		network: DefaultNetProtocol,
+		address: DefaultNetAddress,
+		timeout: 1 * time.Second,
+		health:  health.NewServer(),
+		keepalive: keepalive.ServerParameters{
+			// 连接最大空闲时间，超过这个时间如果没有数据传输，连接可能会被关闭；
+			MaxConnectionIdle: 15 * time.Second,
+			// 连接的最大存活时间，超过这个时间连接也会被关闭(默认值是无限（infinity）)
+			MaxConnectionAge: 30 * time.Minute,
+			// 服务端每隔10秒钟发送 Keep-Alive 消息(默认值是 2 小时)
+			Time: 10 * time.Second,
+			// 超过3秒钟没收到 keep-alive 的消息认为此连接无效(默认值是 20 秒)
+			Timeout: 3 * time.Second,
+		},
+		enforcement: keepalive.EnforcementPolicy{
+			// 客户端每隔10秒发送 Keep-Alive 消息(包括没有请求时), 默认的5分钟会断开这些客户端
+			MinTime:             5 * time.Second,
+			PermitWithoutStream: true,
+		},
+	}
 	for _, o := range opts {
 		o(server)
 	}
 	server.TraceDecorator()
 	server.RecoveryDecorator()
+	server.ValidationDecorator()
+	server.TimeoutDecorator()
+	server.LimitDecorator()
+	server.ErrorDecorator()
+	server.LoggingDecorator()
 	grpcOpts := []grpc.ServerOption{
 		grpc.ChainUnaryInterceptor(server.unaryInterceptor...),
 		grpc.ChainStreamInterceptor(server.streamInterceptor...),
+		grpc.KeepaliveParams(server.keepalive),
+		grpc.KeepaliveEnforcementPolicy(server.enforcement),
 	}
-	keepAliveOpt := grpc.KeepaliveParams(keepalive.ServerParameters{
-		// 连接最大空闲时间，超过这个时间如果没有数据传输，连接可能会被关闭；
-		MaxConnectionIdle: 15 * time.Second,
-		// 连接的最大存活时间，超过这个时间连接也会被关闭(默认值是无限（infinity）)
-		MaxConnectionAge: 30 * time.Minute,
-		// 服务端每隔10秒钟发送 Keep-Alive 消息(默认值是 2 小时)
-		Time: 10 * time.Second,
-		// 超过3秒钟没收到 keep-alive 的消息认为此连接无效(默认值是 20 秒)
-		Timeout: 3 * time.Second,
-	})
-	grpcOpts = append(grpcOpts, keepAliveOpt)
-	server.grpcOpts = append(server.grpcOpts, grpcOpts...)
+	if server.tls.enabled() {
+		// 证书文件修改后自动重新加载, 加载失败时Start返回错误
+		creds, err := server.tls.credentials(true)
+		if err != nil {
+			logger.Errorf("[gRPC] load tls credentials catch err=%v", err)
+			server.err = err
+		} else {
+			grpcOpts = append(grpcOpts, grpc.Creds(creds))
+		}
+	}
+	// Options 的参数在后, 覆盖默认的参数
+	server.grpcOpts = append(grpcOpts, server.grpcOpts...)
 	server.Server = grpc.NewServer(server.grpcOpts...)
 	server.health.SetServingStatus(HealthcheckService, grpc_health_v1.HealthCheckResponse_SERVING)
 	grpc_health_v1.RegisterHealthServer(server.Server, server.health)
 	reflection.Register(server.Server)
+	if server.adminToken != "" {
+		registerAdmin(server.Server, server.adminToken)
+	}
 	return server
 }
 
@@ -86,26 +126,53 @@ func (s *Server) Start(ctx context.Context) error {
 	return s.Serve(s.listener)
 }
 
+// Stop gracefully stops the server, it is force stopped once ctx is done.
 func (s *Server) Stop(ctx context.Context) error {
-	s.GracefulStop()
 	s.health.Shutdown()
 	logger.Info("[gRPC] server stopping")
-	return nil
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		logger.Warn("[gRPC] server graceful stop timeout, force stopping")
+		s.Server.Stop()
+		return ctx.Err()
+	}
+}
+
+// Endpoint binds the listener and returns the real endpoint of the server.
+func (s *Server) Endpoint() (*url.URL, error) {
+	return s.endpointListen()
+}
+
+// CloseListener closes the listener bound by Endpoint when the server is not started.
+func (s *Server) CloseListener() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
 }
 
 // endpointListen return a real address to registry endpoint
 func (s *Server) endpointListen() (*url.URL, error) {
 	s.once.Do(func() {
+		if s.err != nil {
+			return
+		}
 		lis, err := net.Listen(s.network, s.address)
 		if err != nil {
 			s.err = err
 			return
 		}
-		addr, err := tool.Extract(s.address, s.listener)
+		addr, err := tool.Extract(s.address, lis)
 		if err != nil {
-			err := lis.Close()
-			if err != nil {
-				logger.Errorf("close %s listener catch err=%v", s.address, err)
+			if closeErr := lis.Close(); closeErr != nil {
+				logger.Errorf("close %s listener catch err=%v", s.address, closeErr)
 			}
 			s.err = err
 			return
@@ -0,0 +1,28 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"google.golang.org/grpc"
+)
+
+// ErrorDecorator decorator the conversion of the errors.Error returned by the handlers to
+// a status with an errdetails.ErrorInfo, the wrapping errors are not sent to the clients.
+func (s *Server) ErrorDecorator() {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, errors.GRPCError(err)
+		}
+		return resp, nil
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return errors.GRPCError(err)
+		}
+		return nil
+	}
+	s.unaryInterceptor = append([]grpc.UnaryServerInterceptor{unary}, s.unaryInterceptor...)
+	s.streamInterceptor = append([]grpc.StreamServerInterceptor{stream}, s.streamInterceptor...)
+}
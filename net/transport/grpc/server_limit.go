@@ -0,0 +1,305 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"github.com/weiqiangxu/micro_project/common-config/ratelimit"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// CallerKey is the metadata key identifying the caller for CallerRateLimit.
+	CallerKey = "x-caller-id"
+	// RetryAfterKey is the trailer of the rejected calls with the seconds to wait before retrying.
+	RetryAfterKey = "retry-after"
+
+	ShedMethodLabel = "method"
+	ShedReasonLabel = "reason"
+
+	ShedRateLimit        = "rate_limit"
+	ShedCallerRateLimit  = "caller_rate_limit"
+	ShedConcurrencyLimit = "concurrency_limit"
+)
+
+// ServerShedCounter 1.被限流拒绝的请求数, 按方法和原因区分
+var ServerShedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "grpc_server_shed_total",
+	Help: "gRPC服务端限流拒绝的请求数",
+}, []string{ShedMethodLabel, ShedReasonLabel})
+
+// ServerConcurrencyLimitGauge 2.自适应并发限制当前的并发上限
+var ServerConcurrencyLimitGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "grpc_server_concurrency_limit",
+	Help: "gRPC服务端自适应并发限制的当前上限",
+})
+
+// ServerInflightGauge 3.服务端正在处理的请求数
+var ServerInflightGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "grpc_server_inflight",
+	Help: "gRPC服务端正在处理的请求数",
+})
+
+// Limit is a token bucket of Rate calls per second with bursts of Burst calls,
+// Burst defaults to Rate rounded up, at least 1.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// CallerFunc returns the caller of a call, the calls with an empty caller are not limited.
+type CallerFunc func(ctx context.Context) string
+
+// DefaultCaller is the CallerKey metadata of the call, or the IP of the peer.
+func DefaultCaller(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(CallerKey); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// callerBuckets is a token bucket per caller, the full buckets are dropped every sweep interval.
+type callerBuckets struct {
+	limit   Limit
+	caller  CallerFunc
+	mu      sync.Mutex
+	buckets map[string]*ratelimit.Bucket
+	swept   time.Time
+}
+
+const callerSweepInterval = time.Minute
+
+func (c *callerBuckets) allow(ctx context.Context, now time.Time) (bool, time.Duration) {
+	caller := c.caller(ctx)
+	if caller == "" {
+		return true, 0
+	}
+	c.mu.Lock()
+	if now.Sub(c.swept) > callerSweepInterval {
+		for k, b := range c.buckets {
+			if b.Full(now) {
+				delete(c.buckets, k)
+			}
+		}
+		c.swept = now
+	}
+	b, ok := c.buckets[caller]
+	if !ok {
+		b = ratelimit.NewBucket(c.limit.Rate, c.limit.Burst, now)
+		c.buckets[caller] = b
+	}
+	c.mu.Unlock()
+	return b.Allow(now)
+}
+
+// ConcurrencyLimitConfig is the adaptive concurrency limit, a gradient limiter raising the
+// limit while the latency stays at its long term average and lowering it once the latency
+// grows with the in-flight calls.
+type ConcurrencyLimitConfig struct {
+	// InitialLimit is the limit before the first samples.
+	InitialLimit int
+	// MinLimit and MaxLimit bound the limit.
+	MinLimit int
+	MaxLimit int
+	// Smoothing is the weight of a new limit, between 0 and 1.
+	Smoothing float64
+	// Tolerance is the latency growth tolerated before lowering the limit, e.g. 1.5 for 50%.
+	Tolerance float64
+}
+
+// DefaultConcurrencyLimitConfig starts at 100 in-flight calls, bounded to [10, 1000].
+func DefaultConcurrencyLimitConfig() ConcurrencyLimitConfig {
+	return ConcurrencyLimitConfig{InitialLimit: 100, MinLimit: 10, MaxLimit: 1000, Smoothing: 0.2, Tolerance: 1.5}
+}
+
+// concurrencyLimiter is a gradient limiter: limit = limit * longRtt / rtt + sqrt(limit).
+type concurrencyLimiter struct {
+	config   ConcurrencyLimitConfig
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	longRtt  float64
+}
+
+// longRttWeight is the weight of a sample in the long term average latency.
+const longRttWeight = 0.05
+
+func newConcurrencyLimiter(config ConcurrencyLimitConfig) *concurrencyLimiter {
+	defaults := DefaultConcurrencyLimitConfig()
+	if config.InitialLimit <= 0 {
+		config.InitialLimit = defaults.InitialLimit
+	}
+	if config.MinLimit <= 0 {
+		config.MinLimit = 1
+	}
+	if config.MaxLimit < config.MinLimit {
+		config.MaxLimit = defaults.MaxLimit
+	}
+	if config.Smoothing <= 0 || config.Smoothing > 1 {
+		config.Smoothing = defaults.Smoothing
+	}
+	if config.Tolerance < 1 {
+		config.Tolerance = defaults.Tolerance
+	}
+	ServerConcurrencyLimitGauge.Set(float64(config.InitialLimit))
+	return &concurrencyLimiter{config: config, limit: float64(config.InitialLimit)}
+}
+
+// acquire takes an in-flight slot, release must be called with the latency of the call.
+func (c *concurrencyLimiter) acquire() (release func(rtt time.Duration), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight >= int(c.limit) {
+		return nil, false
+	}
+	c.inflight++
+	ServerInflightGauge.Inc()
+	inflight := c.inflight
+	return func(rtt time.Duration) {
+		c.release(rtt, inflight)
+	}, true
+}
+
+func (c *concurrencyLimiter) release(rtt time.Duration, inflight int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inflight--
+	ServerInflightGauge.Dec()
+	sample := float64(rtt)
+	if sample <= 0 {
+		return
+	}
+	if c.longRtt == 0 {
+		c.longRtt = sample
+		return
+	}
+	c.longRtt = c.longRtt*(1-longRttWeight) + sample*longRttWeight
+	// 并发远低于上限时延迟不能说明上限是否合适, 只允许降低上限
+	gradient := math.Max(0.5, math.Min(1, c.config.Tolerance*c.longRtt/sample))
+	newLimit := c.limit*gradient + math.Sqrt(c.limit)
+	if float64(inflight) < c.limit/2 && newLimit > c.limit {
+		return
+	}
+	limit := c.limit*(1-c.config.Smoothing) + newLimit*c.config.Smoothing
+	c.limit = math.Max(float64(c.config.MinLimit), math.Min(float64(c.config.MaxLimit), limit))
+	ServerConcurrencyLimitGauge.Set(c.limit)
+}
+
+// retryAfter is the time for an in-flight call to complete.
+func (c *concurrencyLimiter) retryAfter() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.longRtt == 0 {
+		return time.Second
+	}
+	return time.Duration(c.longRtt)
+}
+
+// limiter is the rate and concurrency limits of a server.
+type limiter struct {
+	methods     map[string]*ratelimit.Bucket
+	callers     *callerBuckets
+	concurrency *concurrencyLimiter
+	now         func() time.Time
+}
+
+// LimitDecorator decorator the rate limits and the concurrency limit to server, the calls
+// over the limits are rejected with ResourceExhausted and a retry-after trailer.
+func (s *Server) LimitDecorator() {
+	l := s.limiter
+	if l == nil {
+		return
+	}
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := l.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		begin := l.now()
+		defer func() { release(l.now().Sub(begin)) }()
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := l.acquire(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		// 流的时长不代表服务端的延迟, 不参与并发上限的计算
+		defer release(0)
+		return handler(srv, ss)
+	}
+	s.unaryInterceptor = append([]grpc.UnaryServerInterceptor{unary}, s.unaryInterceptor...)
+	s.streamInterceptor = append([]grpc.StreamServerInterceptor{stream}, s.streamInterceptor...)
+}
+
+// acquire checks the limits of a call, the health checks are never limited.
+func (l *limiter) acquire(ctx context.Context, method string) (func(time.Duration), error) {
+	noop := func(time.Duration) {}
+	if strings.HasPrefix(method, "/"+HealthcheckService+"/") {
+		return noop, nil
+	}
+	now := l.now()
+	// 先检查调用方的限制, 被拒绝的调用方不消耗方法的令牌
+	if l.callers != nil {
+		if ok, retryAfter := l.callers.allow(ctx, now); !ok {
+			return nil, shed(ctx, method, ShedCallerRateLimit, retryAfter)
+		}
+	}
+	bucket, ok := l.methods[method]
+	if !ok {
+		bucket = l.methods["*"]
+	}
+	if bucket != nil {
+		if ok, retryAfter := bucket.Allow(now); !ok {
+			return nil, shed(ctx, method, ShedRateLimit, retryAfter)
+		}
+	}
+	if l.concurrency != nil {
+		release, ok := l.concurrency.acquire()
+		if !ok {
+			return nil, shed(ctx, method, ShedConcurrencyLimit, l.concurrency.retryAfter())
+		}
+		return release, nil
+	}
+	return noop, nil
+}
+
+// shed counts the rejected call and returns the ResourceExhausted error with the retry delay.
+func shed(ctx context.Context, method, reason string, retryAfter time.Duration) error {
+	ServerShedCounter.WithLabelValues(method, reason).Inc()
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterKey, strconv.Itoa(seconds)))
+	return errors.ErrTooManyRequests.
+		WithMessage("%s rejected by %s, retry after %v", method, reason, retryAfter).
+		WithMetadata(map[string]string{"reason": reason}).
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+}
+
+func (s *Server) limits() *limiter {
+	if s.limiter == nil {
+		s.limiter = &limiter{methods: make(map[string]*ratelimit.Bucket), now: time.Now}
+	}
+	return s.limiter
+}
@@ -0,0 +1,129 @@
+package grpc
+
+import (
+	"context"
+	stdErrors "errors"
+	"testing"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"github.com/weiqiangxu/micro_project/protocol/user"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// runCalls runs n concurrent calls of latency rtt on c, it returns the number of rejected calls.
+func runCalls(c *concurrencyLimiter, n int, rtt time.Duration) int {
+	var releases []func(time.Duration)
+	rejected := 0
+	for i := 0; i < n; i++ {
+		release, ok := c.acquire()
+		if !ok {
+			rejected++
+			continue
+		}
+		releases = append(releases, release)
+	}
+	for _, release := range releases {
+		release(rtt)
+	}
+	return rejected
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	c := newConcurrencyLimiter(ConcurrencyLimitConfig{InitialLimit: 4, MinLimit: 2, MaxLimit: 8, Smoothing: 1})
+	if rejected := runCalls(c, 5, 10*time.Millisecond); rejected != 1 {
+		t.Fatalf("rejected %d calls over the limit 4, want 1", rejected)
+	}
+	// a steady latency at the limit raises it up to MaxLimit
+	for i := 0; i < 5; i++ {
+		runCalls(c, int(c.limit), 10*time.Millisecond)
+	}
+	if c.limit != 8 {
+		t.Errorf("limit = %v with a steady latency, want the max limit 8", c.limit)
+	}
+	// the calls far below the limit do not raise it
+	c.limit = 6
+	runCalls(c, 1, 10*time.Millisecond)
+	if c.limit != 6 {
+		t.Errorf("limit = %v after a call below the limit, want 6", c.limit)
+	}
+	// a latency spike lowers it
+	runCalls(c, int(c.limit), 100*time.Millisecond)
+	if c.limit >= 6 {
+		t.Errorf("limit = %v after a latency spike, want less than 6", c.limit)
+	}
+	if c.inflight != 0 {
+		t.Errorf("inflight = %d, want 0", c.inflight)
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"),
+		RateLimit("/user.Login/GetUserInfo", Limit{Rate: 0.001, Burst: 2}),
+		CallerRateLimit(Limit{Rate: 0.001, Burst: 1}, nil),
+	)
+	user.RegisterLoginServer(srv, loginServer{})
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer srv.Server.Stop()
+	conn, err := Dial(context.Background(), WithEndpoint(endpoint.Host), WithInSecure(true))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	client := user.NewLoginClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name       string
+		caller     string
+		wantReason string
+	}{
+		{"first call of a", "a", ""},
+		{"second call of a", "a", ShedCallerRateLimit},
+		{"first call of b", "b", ""},
+		{"method limit", "c", ShedRateLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trailer metadata.MD
+			callCtx := metadata.AppendToOutgoingContext(ctx, CallerKey, tt.caller)
+			_, err := client.GetUserInfo(callCtx, &user.GetUserInfoRequest{UniqueId: "1"}, grpc.Trailer(&trailer))
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("GetUserInfo() error = %v", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.ResourceExhausted || !stdErrors.Is(err, errors.ErrTooManyRequests) {
+				t.Fatalf("GetUserInfo() error = %v, want %v", err, codes.ResourceExhausted)
+			}
+			var e *errors.Error
+			stdErrors.As(err, &e)
+			if e.Metadata["reason"] != tt.wantReason {
+				t.Errorf("reason = %s, want %s", e.Metadata["reason"], tt.wantReason)
+			}
+			if len(e.Details()) != 1 || e.Details()[0].(*errdetails.RetryInfo).GetRetryDelay().AsDuration() <= 0 {
+				t.Errorf("details = %v, want a RetryInfo", e.Details())
+			}
+			if v := trailer.Get(RetryAfterKey); len(v) != 1 || v[0] == "" {
+				t.Errorf("trailer %s = %v, want the seconds to wait", RetryAfterKey, v)
+			}
+		})
+	}
+	// the health checks are never limited
+	for i := 0; i < 3; i++ {
+		if _, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+			t.Errorf("Check() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+	appNet "github.com/weiqiangxu/micro_project/net"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDKey is the metadata key of the request id.
+const RequestIDKey = "x-request-id"
+
+// LoggingDecorator decorator the request scoped log fields to server,
+// it is the outermost interceptor so every other interceptor and the handlers see them.
+func (s *Server) LoggingDecorator() {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(s.loggingContext(ctx, info.FullMethod), req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpcMiddleware.WrapServerStream(ss)
+		wrapped.WrappedContext = s.loggingContext(ss.Context(), info.FullMethod)
+		return handler(srv, wrapped)
+	}
+	s.unaryInterceptor = append([]grpc.UnaryServerInterceptor{unary}, s.unaryInterceptor...)
+	s.streamInterceptor = append([]grpc.StreamServerInterceptor{stream}, s.streamInterceptor...)
+}
+
+// loggingContext carries the app info, the method and the request id for logger.Ctx
+func (s *Server) loggingContext(ctx context.Context, method string) context.Context {
+	if s.ctx != nil {
+		if info, ok := appNet.FromContext(s.ctx); ok {
+			ctx = appNet.NewContext(ctx, info)
+		}
+	}
+	fields := []interface{}{"grpc_method", method}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RequestIDKey); len(v) > 0 {
+			fields = append(fields, "request_id", v[0])
+		}
+	}
+	return logger.WithFields(ctx, fields...)
+}
@@ -1,6 +1,13 @@
 package grpc
 
-import "google.golang.org/grpc"
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
 
 type ServerOption func(o *Server)
 
@@ -10,9 +17,156 @@ func UnaryInterceptor(in ...grpc.UnaryServerInterceptor) ServerOption {
 	}
 }
 
+// StreamInterceptor with the interceptors of the streaming RPCs.
+func StreamInterceptor(in ...grpc.StreamServerInterceptor) ServerOption {
+	return func(s *Server) {
+		s.streamInterceptor = in
+	}
+}
+
+// Options with gRPC server options, they are applied after the ones of the other options.
+func Options(opts ...grpc.ServerOption) ServerOption {
+	return func(s *Server) {
+		s.grpcOpts = append(s.grpcOpts, opts...)
+	}
+}
+
+// Tracing with the OpenTelemetry tracing interceptors.
+func Tracing(tracing bool) ServerOption {
+	return func(s *Server) {
+		s.tracing = tracing
+	}
+}
+
+// Recovery with the interceptors recovering the panics of the handlers as errors.
+func Recovery(recovery bool) ServerOption {
+	return func(s *Server) {
+		s.recovery = recovery
+	}
+}
+
+// Timeout is the default deadline of the unary calls without a deadline from the client,
+// 0 disables it.
+func Timeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.timeout = timeout
+	}
+}
+
+// MethodTimeout overrides the default deadline of a method, e.g. /user.Login/GetUserInfo,
+// it also applies to the streams of the method.
+func MethodTimeout(method string, timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		if s.methodTimeouts == nil {
+			s.methodTimeouts = make(map[string]time.Duration)
+		}
+		s.methodTimeouts[method] = timeout
+	}
+}
+
+// KeepaliveParams replaces the default keepalive parameters of the server.
+func KeepaliveParams(kp keepalive.ServerParameters) ServerOption {
+	return func(s *Server) {
+		s.keepalive = kp
+	}
+}
+
+// KeepaliveEnforcementPolicy replaces the default keepalive enforcement policy of the server,
+// the clients pinging more often than it allows are disconnected.
+func KeepaliveEnforcementPolicy(kep keepalive.EnforcementPolicy) ServerOption {
+	return func(s *Server) {
+		s.enforcement = kep
+	}
+}
+
+// MaxRecvMsgSize is the max size in bytes of the received messages, 4MB by default.
+func MaxRecvMsgSize(size int) ServerOption {
+	return func(s *Server) {
+		s.grpcOpts = append(s.grpcOpts, grpc.MaxRecvMsgSize(size))
+	}
+}
+
+// MaxSendMsgSize is the max size in bytes of the sent messages.
+func MaxSendMsgSize(size int) ServerOption {
+	return func(s *Server) {
+		s.grpcOpts = append(s.grpcOpts, grpc.MaxSendMsgSize(size))
+	}
+}
+
+// MaxConcurrentStreams limits the number of concurrent streams of each connection.
+func MaxConcurrentStreams(n uint32) ServerOption {
+	return func(s *Server) {
+		s.grpcOpts = append(s.grpcOpts, grpc.MaxConcurrentStreams(n))
+	}
+}
+
 // Address with server address.
 func Address(addr string) ServerOption {
 	return func(s *Server) {
 		s.address = addr
 	}
 }
+
+// Admin registers the admin service protected by token, an empty token disables it.
+func Admin(token string) ServerOption {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+// TLSConfig serves TLS with config, the certificates of TLS and ClientCA are added to it.
+func TLSConfig(config *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tls.config = config
+	}
+}
+
+// TLS serves TLS with the certificate and key files, they are reloaded once modified on disk.
+func TLS(certFile, keyFile string) ServerOption {
+	return func(s *Server) {
+		s.tls.certFile = certFile
+		s.tls.keyFile = keyFile
+	}
+}
+
+// ClientCA requires the clients to present a certificate signed by the CA bundle of caFile (mutual TLS),
+// the bundle is reloaded once modified on disk.
+func ClientCA(caFile string) ServerOption {
+	return func(s *Server) {
+		s.tls.caFile = caFile
+	}
+}
+
+// CertReloadInterval is the minimum interval between two checks of the certificate files,
+// DefaultCertReloadInterval if not set.
+func CertReloadInterval(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		s.tls.reloadInterval = interval
+	}
+}
+
+// RateLimit limits the calls of method, e.g. /user.Login/GetUserInfo, with a token bucket,
+// the method * limits every method without its own limit.
+func RateLimit(method string, limit Limit) ServerOption {
+	return func(s *Server) {
+		s.limits().methods[method] = ratelimit.NewBucket(limit.Rate, limit.Burst, time.Now())
+	}
+}
+
+// CallerRateLimit limits the calls of every caller with a token bucket, the callers are
+// identified by caller or DefaultCaller if nil.
+func CallerRateLimit(limit Limit, caller CallerFunc) ServerOption {
+	return func(s *Server) {
+		if caller == nil {
+			caller = DefaultCaller
+		}
+		s.limits().callers = &callerBuckets{limit: limit, caller: caller, buckets: make(map[string]*ratelimit.Bucket)}
+	}
+}
+
+// ConcurrencyLimit limits the in-flight calls with an adaptive limit following the latency.
+func ConcurrencyLimit(config ConcurrencyLimitConfig) ServerOption {
+	return func(s *Server) {
+		s.limits().concurrency = newConcurrencyLimiter(config)
+	}
+}
@@ -16,9 +16,10 @@ func (s *Server) RecoveryDecorator() {
 			grpcRecovery.WithRecoveryHandlerContext(func(ctx context.Context, p interface{}) (err error) {
 				md, ok := metadata.FromIncomingContext(ctx)
 				if ok {
-					logger.Errorf("gRPC metadata: %v panic info: %v", md, p)
+					// 脱敏后再输出, 避免泄露authorization等认证信息
+					logger.Ctx(ctx).Errorf("gRPC metadata: %v panic info: %v", logger.RedactMetadata(md), p)
 				} else {
-					logger.Error(p)
+					logger.Ctx(ctx).Error(p)
 				}
 				return fmt.Errorf("context panic triggered: %v", p)
 			}),
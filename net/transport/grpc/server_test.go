@@ -1 +1,233 @@
 package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+	"github.com/weiqiangxu/micro_project/net/registry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestServer_Endpoint(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	if endpoint.Scheme != SchemeOfGrpc || endpoint.Port() == "0" {
+		t.Errorf("Endpoint() = %v, want a bound grpc endpoint", endpoint)
+	}
+	if again, _ := srv.Endpoint(); again != endpoint {
+		t.Errorf("Endpoint() = %v, want the same endpoint %v", again, endpoint)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
+
+func TestDial_Discovery(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"))
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer func() { _ = srv.Stop(context.Background()) }()
+	d := registry.NewMemory()
+	instance := &registry.ServiceInstance{ID: "1", Name: "user-service", Endpoints: []string{endpoint.String()}}
+	if err := d.Register(context.Background(), instance); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	conn, err := Dial(context.Background(),
+		WithEndpoint("discovery:///user-service"),
+		WithInSecure(true),
+		WithDiscovery(d),
+	)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Errorf("Check() status = %v, want %v", resp.Status, grpc_health_v1.HealthCheckResponse_SERVING)
+	}
+}
+
+func TestServer_Admin(t *testing.T) {
+	defer logger.SetLevel(logger.InfoLevel)
+	srv := NewServer(Address("127.0.0.1:0"), Admin("secret"))
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer func() { _ = srv.Stop(context.Background()) }()
+	conn, err := Dial(context.Background(), WithEndpoint(endpoint.Host), WithInSecure(true))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := NewAdminClient(conn, "wrong").GetLogLevel(ctx, ""); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetLogLevel() error = %v, want %v", err, codes.Unauthenticated)
+	}
+	admin := NewAdminClient(conn, "secret")
+	if _, err := admin.SetLogLevel(ctx, "", "debug", time.Minute); err != nil {
+		t.Fatalf("SetLogLevel() error = %v", err)
+	}
+	resp, err := admin.GetLogLevel(ctx, "")
+	if err != nil {
+		t.Fatalf("GetLogLevel() error = %v", err)
+	}
+	if level := resp.GetFields()["level"].GetStringValue(); level != "debug" {
+		t.Errorf("GetLogLevel() level = %s, want debug", level)
+	}
+	if _, err := admin.GetLogLevel(ctx, "unknown"); status.Code(err) != codes.NotFound {
+		t.Errorf("GetLogLevel() error = %v, want %v", err, codes.NotFound)
+	}
+}
+
+// testServiceDesc is a service whose unary methods wait for their deadline, panic or fail,
+// and whose stream method waits for its deadline.
+var testServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Test",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Wait", Handler: testHandler("/test.Test/Wait", func(ctx context.Context) error {
+			<-ctx.Done()
+			return status.FromContextError(ctx.Err()).Err()
+		})},
+		{MethodName: "Panic", Handler: testHandler("/test.Test/Panic", func(ctx context.Context) error {
+			panic("test panic")
+		})},
+		{MethodName: "Error", Handler: testHandler("/test.Test/Error", func(ctx context.Context) error {
+			return fmt.Errorf("get user: %w", errors.ErrInvalidParams.WithMessage("uid is required").WithMetadata(map[string]string{"field": "uid"}))
+		})},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "WaitStream", ServerStreams: true, Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			<-stream.Context().Done()
+			return status.FromContextError(stream.Context().Err()).Err()
+		}},
+	},
+}
+
+func testHandler(method string, fn func(ctx context.Context) error) func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(emptypb.Empty)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return new(emptypb.Empty), fn(ctx)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, handler)
+	}
+}
+
+func dialTestService(t *testing.T, opts ...ServerOption) *grpc.ClientConn {
+	t.Helper()
+	srv := NewServer(append([]ServerOption{Address("127.0.0.1:0")}, opts...)...)
+	srv.RegisterService(&testServiceDesc, struct{}{})
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	t.Cleanup(func() { srv.Server.Stop() })
+	conn, err := Dial(context.Background(), WithEndpoint(endpoint.Host), WithInSecure(true))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestServer_Timeout(t *testing.T) {
+	conn := dialTestService(t,
+		Timeout(50*time.Millisecond),
+		MethodTimeout("/test.Test/WaitStream", 50*time.Millisecond),
+	)
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		wantCode codes.Code
+		wantMin  time.Duration
+	}{
+		{"default deadline", 0, codes.DeadlineExceeded, 50 * time.Millisecond},
+		{"client deadline kept", 150 * time.Millisecond, codes.DeadlineExceeded, 150 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			begin := time.Now()
+			err := conn.Invoke(ctx, "/test.Test/Wait", new(emptypb.Empty), new(emptypb.Empty))
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Invoke() error = %v, want %v", err, tt.wantCode)
+			}
+			if elapsed := time.Since(begin); elapsed < tt.wantMin || elapsed > tt.wantMin+time.Second {
+				t.Errorf("Invoke() returned after %v, want about %v", elapsed, tt.wantMin)
+			}
+		})
+	}
+	t.Run("stream method timeout", func(t *testing.T) {
+		desc := &grpc.StreamDesc{StreamName: "WaitStream", ServerStreams: true}
+		stream, err := conn.NewStream(context.Background(), desc, "/test.Test/WaitStream")
+		if err != nil {
+			t.Fatalf("NewStream() error = %v", err)
+		}
+		_ = stream.SendMsg(new(emptypb.Empty))
+		_ = stream.CloseSend()
+		if err := stream.RecvMsg(new(emptypb.Empty)); status.Code(err) != codes.DeadlineExceeded {
+			t.Errorf("RecvMsg() error = %v, want %v", err, codes.DeadlineExceeded)
+		}
+	})
+}
+
+func TestServer_Recovery(t *testing.T) {
+	conn := dialTestService(t, Recovery(true))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := conn.Invoke(ctx, "/test.Test/Panic", new(emptypb.Empty), new(emptypb.Empty))
+	if err == nil || status.Code(err) == codes.Unavailable {
+		t.Errorf("Invoke() error = %v, want the recovered panic", err)
+	}
+	// the server survives the panic
+	if err := conn.Invoke(ctx, "/grpc.health.v1.Health/Check", &grpc_health_v1.HealthCheckRequest{}, new(grpc_health_v1.HealthCheckResponse)); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestServer_MaxRecvMsgSize(t *testing.T) {
+	conn := dialTestService(t, MaxRecvMsgSize(16), Timeout(0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req := &grpc_health_v1.HealthCheckRequest{Service: strings.Repeat("s", 32)}
+	err := conn.Invoke(ctx, "/grpc.health.v1.Health/Check", req, new(grpc_health_v1.HealthCheckResponse))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Check() error = %v, want %v", err, codes.ResourceExhausted)
+	}
+}
@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+)
+
+// TimeoutDecorator decorator the default deadline to server, the calls without a deadline
+// from the client are canceled after the timeout of their method.
+//
+// The unary calls use Timeout unless MethodTimeout overrides it, the streams are usually
+// long-lived so only the ones with a MethodTimeout get a deadline.
+func (s *Server) TimeoutDecorator() {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		timeout, ok := s.methodTimeouts[info.FullMethod]
+		if !ok {
+			timeout = s.timeout
+		}
+		ctx, cancel := withDefaultTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		timeout, ok := s.methodTimeouts[info.FullMethod]
+		if !ok {
+			return handler(srv, ss)
+		}
+		ctx, cancel := withDefaultTimeout(ss.Context(), timeout)
+		defer cancel()
+		wrapped := grpcMiddleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+	s.unaryInterceptor = append([]grpc.UnaryServerInterceptor{unary}, s.unaryInterceptor...)
+	s.streamInterceptor = append([]grpc.StreamServerInterceptor{stream}, s.streamInterceptor...)
+}
+
+// withDefaultTimeout keeps the deadline of ctx, a timeout not positive means no deadline.
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
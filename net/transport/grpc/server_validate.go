@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	stdErrors "errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+)
+
+// Validator is implemented by the request messages validating themselves, e.g. the messages
+// generated by protoc-gen-validate. Validate may return validator.ValidationErrors or an
+// errors.Error, which is returned as is.
+type Validator interface {
+	Validate() error
+}
+
+var (
+	validate      = newValidate()
+	validateMu    sync.RWMutex
+	validateTypes = make(map[reflect.Type]bool)
+)
+
+func newValidate() *validator.Validate {
+	v := validator.New()
+	// 字段名使用proto中定义的名称, 与客户端看到的字段一致
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, part := range strings.Split(f.Tag.Get("protobuf"), ",") {
+			if name, ok := strings.CutPrefix(part, "name="); ok {
+				return name
+			}
+		}
+		return f.Name
+	})
+	return v
+}
+
+// RegisterRules validates the messages of the type of msg with the go-playground validator
+// rules of their fields, e.g. RegisterRules(&user.GetUserInfoRequest{}, map[string]string{"UniqueId": "required"}).
+//
+// The rules are checked before the Validate method of the messages implementing Validator.
+func RegisterRules(msg interface{}, rules map[string]string) {
+	validateMu.Lock()
+	defer validateMu.Unlock()
+	validate.RegisterStructValidationMapRules(rules, msg)
+	validateTypes[reflect.TypeOf(msg)] = true
+}
+
+// ValidateMessage validates msg with its registered rules and its Validate method, the
+// violations are returned as an errors.ErrInvalidParams with an errdetails.BadRequest.
+func ValidateMessage(msg interface{}) error {
+	if err := validateRules(msg); err != nil {
+		return invalidArgument(err)
+	}
+	if v, ok := msg.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return invalidArgument(err)
+		}
+	}
+	return nil
+}
+
+func validateRules(msg interface{}) error {
+	validateMu.RLock()
+	defer validateMu.RUnlock()
+	if !validateTypes[reflect.TypeOf(msg)] {
+		return nil
+	}
+	return validate.Struct(msg)
+}
+
+// invalidArgument converts a validation error to an errors.ErrInvalidParams with the field violations.
+func invalidArgument(err error) error {
+	var e *errors.Error
+	if stdErrors.As(err, &e) {
+		return e
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	var validationErrors validator.ValidationErrors
+	if stdErrors.As(err, &validationErrors) {
+		for _, fe := range validationErrors {
+			// 去掉最外层的消息名, 如 GetUserInfoRequest.unique_id
+			field := fe.Namespace()
+			if i := strings.Index(field, "."); i >= 0 {
+				field = field[i+1:]
+			}
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field, Description: fe.Error()})
+		}
+	} else {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Description: err.Error()})
+	}
+	return errors.ErrInvalidParams.WithMessage("%s", err.Error()).WithCause(err).WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+}
+
+// ValidationDecorator decorator the validation of the request messages to server, the
+// invalid requests are rejected with InvalidArgument before reaching the handlers.
+func (s *Server) ValidationDecorator() {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := ValidateMessage(req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingServerStream{ServerStream: ss})
+	}
+	s.unaryInterceptor = append(s.unaryInterceptor, unary)
+	s.streamInterceptor = append(s.streamInterceptor, stream)
+}
+
+// validatingServerStream validates every message received from the client.
+type validatingServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return ValidateMessage(m)
+}
@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/errors"
+	"github.com/weiqiangxu/micro_project/protocol/user"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type validatedRequest struct {
+	Age int
+}
+
+func (r *validatedRequest) Validate() error {
+	if r.Age < 0 {
+		return fmt.Errorf("age %d is negative", r.Age)
+	}
+	return nil
+}
+
+type loginServer struct {
+	user.UnimplementedLoginServer
+}
+
+func (loginServer) GetUserInfo(context.Context, *user.GetUserInfoRequest) (*user.GetUserInfoResponse, error) {
+	return &user.GetUserInfoResponse{}, nil
+}
+
+func badRequest(t *testing.T, err error) *errdetails.BadRequest {
+	t.Helper()
+	var e *errors.Error
+	if !stdErrors.As(err, &e) || !stdErrors.Is(err, errors.ErrInvalidParams) {
+		t.Fatalf("error = %v, want an invalid params error", err)
+	}
+	for _, detail := range e.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			return br
+		}
+	}
+	t.Fatalf("error = %v, want a BadRequest detail", err)
+	return nil
+}
+
+func TestValidateMessage(t *testing.T) {
+	RegisterRules(&user.DeleteUserRequest{}, map[string]string{"Id": "gt=0"})
+	tests := []struct {
+		name      string
+		msg       interface{}
+		wantField string
+	}{
+		{"valid rules", &user.DeleteUserRequest{Id: 1}, ""},
+		{"invalid rules", &user.DeleteUserRequest{}, "id"},
+		{"valid method", &validatedRequest{Age: 1}, ""},
+		{"invalid method", &validatedRequest{Age: -1}, ""},
+		{"not registered", &user.UserInfo{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessage(tt.msg)
+			wantErr := tt.wantField != "" || tt.name == "invalid method"
+			if (err != nil) != wantErr {
+				t.Fatalf("ValidateMessage() error = %v, wantErr %v", err, wantErr)
+			}
+			if err == nil {
+				return
+			}
+			violations := badRequest(t, err).GetFieldViolations()
+			if len(violations) != 1 || violations[0].GetField() != tt.wantField {
+				t.Errorf("violations = %v, want one of field %q", violations, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestServer_Validation(t *testing.T) {
+	RegisterRules(&user.GetUserInfoRequest{}, map[string]string{"UniqueId": "required", "NameMain": "max=4"})
+	srv := NewServer(Address("127.0.0.1:0"))
+	user.RegisterLoginServer(srv, loginServer{})
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	defer srv.Server.Stop()
+	conn, err := Dial(context.Background(), WithEndpoint(endpoint.Host), WithInSecure(true))
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	client := user.NewLoginClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := client.GetUserInfo(ctx, &user.GetUserInfoRequest{UniqueId: "1", NameMain: "jack"}); err != nil {
+		t.Fatalf("GetUserInfo() error = %v", err)
+	}
+	_, err = client.GetUserInfo(ctx, &user.GetUserInfoRequest{NameMain: "jack ma"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("GetUserInfo() error = %v, want %v", err, codes.InvalidArgument)
+	}
+	var fields []string
+	for _, v := range badRequest(t, err).GetFieldViolations() {
+		fields = append(fields, v.GetField())
+	}
+	if fmt.Sprint(fields) != "[unique_id name_main]" {
+		t.Errorf("violations of %v, want unique_id and name_main", fields)
+	}
+}
@@ -0,0 +1,212 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+	"google.golang.org/grpc/credentials"
+)
+
+// DefaultCertReloadInterval is the minimum interval between two checks of the certificate files.
+const DefaultCertReloadInterval = 10 * time.Second
+
+// ErrNoCertificates is returned when a CA bundle has no PEM certificate.
+var ErrNoCertificates = errors.New("no certificates found in CA bundle")
+
+// reloader loads a value from files and loads it again once one of the files is modified,
+// the files are checked at most every interval during the TLS handshakes.
+type reloader[T any] struct {
+	files    []string
+	load     func() (T, error)
+	interval time.Duration
+
+	mu      sync.Mutex
+	value   T
+	modTime time.Time
+	checked time.Time
+}
+
+func newReloader[T any](interval time.Duration, load func() (T, error), files ...string) (*reloader[T], error) {
+	r := &reloader[T]{files: files, load: load, interval: interval}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if r.value, err = load(); err != nil {
+		return nil, err
+	}
+	r.modTime, r.checked = modTime, time.Now()
+	return r, nil
+}
+
+func (r *reloader[T]) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range r.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// get returns the loaded value, a failed reload keeps the previous value.
+func (r *reloader[T]) get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if now.Sub(r.checked) < r.interval {
+		return r.value
+	}
+	r.checked = now
+	modTime, err := r.latestModTime()
+	if err != nil || modTime.Equal(r.modTime) {
+		return r.value
+	}
+	value, err := r.load()
+	if err != nil {
+		// the files may be half written, they are loaded again on the next check
+		logger.Errorf("[gRPC] reload %v catch err=%v", r.files, err)
+		return r.value
+	}
+	logger.Infof("[gRPC] reloaded %v", r.files)
+	r.value, r.modTime = value, modTime
+	return r.value
+}
+
+func loadKeyPair(certFile, keyFile string, interval time.Duration) (*reloader[*tls.Certificate], error) {
+	return newReloader(interval, func() (*tls.Certificate, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &cert, nil
+	}, certFile, keyFile)
+}
+
+func loadCertPool(caFile string, interval time.Duration) (*reloader[*x509.CertPool], error) {
+	return newReloader(interval, func() (*x509.CertPool, error) {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: %w", caFile, ErrNoCertificates)
+		}
+		return pool, nil
+	}, caFile)
+}
+
+// tlsFiles is the TLS settings shared by the server and the client options.
+type tlsFiles struct {
+	config         *tls.Config
+	certFile       string
+	keyFile        string
+	caFile         string
+	serverName     string
+	reloadInterval time.Duration
+}
+
+func (f *tlsFiles) enabled() bool {
+	return f.config != nil || f.certFile != "" || f.caFile != ""
+}
+
+// credentials returns transport credentials building the TLS config of every handshake from
+// the base config and the certificate files reloaded from disk.
+//
+// On the server the CA bundle verifies the client certificates (mutual TLS), on the client it
+// verifies the server certificate and the key pair is the client certificate.
+func (f *tlsFiles) credentials(server bool) (credentials.TransportCredentials, error) {
+	interval := f.reloadInterval
+	if interval <= 0 {
+		interval = DefaultCertReloadInterval
+	}
+	var (
+		keyPair *reloader[*tls.Certificate]
+		caPool  *reloader[*x509.CertPool]
+		err     error
+	)
+	if f.certFile != "" || f.keyFile != "" {
+		if keyPair, err = loadKeyPair(f.certFile, f.keyFile, interval); err != nil {
+			return nil, err
+		}
+	}
+	if f.caFile != "" {
+		if caPool, err = loadCertPool(f.caFile, interval); err != nil {
+			return nil, err
+		}
+	}
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	if f.config != nil {
+		base = f.config.Clone()
+	}
+	if f.serverName != "" {
+		base.ServerName = f.serverName
+	}
+	if server && keyPair == nil && len(base.Certificates) == 0 && base.GetCertificate == nil {
+		return nil, errors.New("tls server requires a certificate")
+	}
+	build := func() *tls.Config {
+		c := base.Clone()
+		if keyPair != nil {
+			c.Certificates = []tls.Certificate{*keyPair.get()}
+		}
+		if caPool != nil {
+			if server {
+				c.ClientCAs = caPool.get()
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+			} else {
+				c.RootCAs = caPool.get()
+			}
+		}
+		return c
+	}
+	return &reloadingCredentials{build: build, info: credentials.NewTLS(base).Info()}, nil
+}
+
+// reloadingCredentials is TLS transport credentials with a new tls.Config per handshake.
+type reloadingCredentials struct {
+	build func() *tls.Config
+	info  credentials.ProtocolInfo
+}
+
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.build()).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *reloadingCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.build()).ServerHandshake(conn)
+}
+
+func (c *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return c.info
+}
+
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	clone := *c
+	return &clone
+}
+
+// OverrideServerName is deprecated by gRPC, the server name is set by WithServerName.
+func (c *reloadingCredentials) OverrideServerName(serverName string) error {
+	build := c.build
+	c.build = func() *tls.Config {
+		cfg := build()
+		cfg.ServerName = serverName
+		return cfg
+	}
+	c.info.ServerName = serverName
+	return nil
+}
@@ -0,0 +1,192 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate signed by ca and its key to dir/name.crt and dir/name.key.
+func (ca *testCA) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	return certFile, keyFile
+}
+
+// writeFile writes data with a modification time later than the previous one.
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	modTime := time.Now()
+	if info, err := os.Stat(path); err == nil && !info.ModTime().Before(modTime) {
+		modTime = info.ModTime().Add(time.Second)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func startTLSServer(t *testing.T, opts ...ServerOption) string {
+	t.Helper()
+	srv := NewServer(append([]ServerOption{Address("127.0.0.1:0")}, opts...)...)
+	endpoint, err := srv.Endpoint()
+	if err != nil {
+		t.Fatalf("Endpoint() error = %v", err)
+	}
+	go func() { _ = srv.Start(context.Background()) }()
+	t.Cleanup(func() { srv.Server.Stop() })
+	return endpoint.Host
+}
+
+func healthCheck(t *testing.T, opts ...ClientOption) error {
+	t.Helper()
+	conn, err := Dial(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	return err
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+	serverCert, serverKey := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	addr := startTLSServer(t, TLS(serverCert, serverKey), ClientCA(caFile))
+
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		wantErr bool
+	}{
+		{"mutual tls", []ClientOption{WithCA(caFile), WithClientCert(clientCert, clientKey)}, false},
+		{"server name", []ClientOption{WithCA(caFile), WithClientCert(clientCert, clientKey), WithServerName("localhost")}, false},
+		{"no client certificate", []ClientOption{WithCA(caFile)}, true},
+		{"unknown server name", []ClientOption{WithCA(caFile), WithClientCert(clientCert, clientKey), WithServerName("other")}, true},
+		{"plaintext", []ClientOption{WithInSecure(true)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := healthCheck(t, append(tt.opts, WithEndpoint(addr))...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServer_TLSReload(t *testing.T) {
+	dir := t.TempDir()
+	oldCA, newCA := newTestCA(t, "old"), newTestCA(t, "new")
+	oldCAFile, newCAFile := filepath.Join(dir, "old.crt"), filepath.Join(dir, "new.crt")
+	writeFile(t, oldCAFile, oldCA.pem)
+	writeFile(t, newCAFile, newCA.pem)
+	certFile, keyFile := oldCA.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	addr := startTLSServer(t, TLS(certFile, keyFile), CertReloadInterval(time.Millisecond))
+
+	if err := healthCheck(t, WithEndpoint(addr), WithCA(oldCAFile)); err != nil {
+		t.Fatalf("Check() with the old CA error = %v", err)
+	}
+	// rotate the certificate on disk, the new handshakes use it without restart
+	newCA.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	time.Sleep(5 * time.Millisecond)
+	if err := healthCheck(t, WithEndpoint(addr), WithCA(newCAFile)); err != nil {
+		t.Errorf("Check() with the new CA error = %v", err)
+	}
+	if err := healthCheck(t, WithEndpoint(addr), WithCA(oldCAFile)); err == nil {
+		t.Error("Check() with the old CA succeeded after the rotation")
+	}
+
+	// a broken file keeps the loaded certificate
+	writeFile(t, certFile, []byte("broken"))
+	time.Sleep(5 * time.Millisecond)
+	if err := healthCheck(t, WithEndpoint(addr), WithCA(newCAFile)); err != nil {
+		t.Errorf("Check() after a broken rotation error = %v", err)
+	}
+}
+
+func TestServer_TLSLoadError(t *testing.T) {
+	srv := NewServer(Address("127.0.0.1:0"), TLS("missing.crt", "missing.key"))
+	if _, err := srv.Endpoint(); err == nil {
+		t.Error("Endpoint() error = nil, want the load error")
+	}
+	if err := srv.Start(context.Background()); err == nil {
+		t.Error("Start() error = nil, want the load error")
+	}
+	if _, err := Dial(context.Background(), WithEndpoint("127.0.0.1:0"), WithCA("missing.crt")); err == nil {
+		t.Error("Dial() error = nil, want the load error")
+	}
+}
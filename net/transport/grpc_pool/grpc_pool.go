@@ -1,7 +1,9 @@
 package grpc_pool
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -12,38 +14,119 @@ import (
 // ErrMaxActiveConnReached 连接池超限
 var ErrMaxActiveConnReached = errors.New("MaxActiveConnReached")
 
+// Mode 连接池选择连接的方式
+type Mode int
+
+const (
+	// ModeLease 每次RPC调用独占一个连接, 连接数达到上限时等待其他调用释放连接
+	ModeLease Mode = iota
+	// ModeLeastStreams 多个RPC调用共享连接(HTTP/2多路复用), 选择进行中的调用最少的连接
+	ModeLeastStreams
+	// ModeP2C 多个RPC调用共享连接, 随机选择两个连接中进行中的调用较少的一个(power of two choices)
+	ModeP2C
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeLeastStreams:
+		return "least_streams"
+	case ModeP2C:
+		return "p2c"
+	default:
+		return "lease"
+	}
+}
+
+// ParseMode parses the name of a Mode, the empty name is ModeLease.
+func ParseMode(name string) (Mode, error) {
+	for _, m := range []Mode{ModeLease, ModeLeastStreams, ModeP2C} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	if name == "" {
+		return ModeLease, nil
+	}
+	return ModeLease, errors.Errorf("unknown pool mode %q", name)
+}
+
+// DefaultMaxStreams 共享连接时每个连接进行中的调用数的默认阈值
+const DefaultMaxStreams = 100
+
 // Config 连接池相关配置
 type Config struct {
-	// 连接池中拥有的最小连接数
+	// 连接池名称, 设置时导出Prometheus指标并在 DebugHandler 中返回
+	Name string
+	// 选择连接的方式, 默认 ModeLease
+	Mode Mode
+	// 共享连接时每个连接进行中的调用数的阈值, 所有连接都达到阈值时创建新的连接, 默认 DefaultMaxStreams
+	MaxStreams int
+	// 连接池中拥有的最小连接数(MinActiveConnections), 创建连接池时创建
 	InitialCap int
-	// 最大并发存活连接数
+	// 最大并发存活连接数(MaxActiveConnections), 包括租用中和空闲的连接
 	MaxCap int
-	// 生成连接的方法
+	// 最大空闲连接数(MaxIdleConnections), 释放连接时空闲连接已达到该数量则关闭连接, 0为MaxCap
+	MaxIdle int
+	// 生成连接的方法, 通过连接池发起的调用在完成时释放连接
 	Factory func() (*grpc.ClientConn, error)
-	// 关闭连接的方法
+	// 关闭连接的方法, 默认为 (*grpc.ClientConn).Close
 	Close func(*grpc.ClientConn) error
-	// 检查连接是否有效的方法
+	// 检查连接是否有效的方法, 设置时租用连接之前也会检查, 默认后台使用 HealthCheck("", DefaultPingTimeout) 检查空闲的连接
 	Ping func(*grpc.ClientConn) error
-	// 连接最大空闲时间，超过该事件则将失效
+	// 后台检查空闲连接、替换失败的连接并补充到InitialCap的间隔, 默认 DefaultCheckInterval, 负数则不启动后台检查
+	CheckInterval time.Duration
+	// 连接失败(TRANSIENT_FAILURE)后没有重新连接成功的最长时间, 超过后从连接池移除, 默认 DefaultFailureGrace
+	FailureGrace time.Duration
+	// 连接最大空闲时间，超过该事件则将失效, 共享连接时超过InitialCap的空闲连接将被关闭
 	IdleTimeout time.Duration
+	// 连接数达到MaxCap时等待连接释放的最长时间, 0则只受ctx限制
+	WaitTimeout time.Duration
 }
 
 // channelPool 存放连接信息
 type channelPool struct {
-	mu                 sync.RWMutex                     // lock
-	connections        chan *Conn                       // connection of poll
+	mu                 sync.Mutex
+	ctx                context.Context                  // done once the pool is released
+	cancel             context.CancelFunc               // stop the keeper and the watchers
+	wg                 sync.WaitGroup                   // the keeper and the watchers
+	name               string                           // name of the metrics and the debug handler
+	mode               Mode                             // how connections are selected
+	conns              map[*grpc.ClientConn]*Conn       // open connections
+	idle               []*Conn                          // idle connections of ModeLease, the most recently released last
+	shared             []*Conn                          // connections of the shared modes
+	waiters            []chan struct{}                  // Get waiting for a connection, the first waiting first
 	factory            func() (*grpc.ClientConn, error) // create connection
 	close              func(*grpc.ClientConn) error     // close connection
 	ping               func(*grpc.ClientConn) error     // usage to confirm connection us able
+	pingOnGet          bool                             // ping before leasing a connection
 	idleTimeout        time.Duration                    // every connection maximum duration available
+	failureGrace       time.Duration                    // maximum duration a failed connection reconnects
+	waitTimeout        time.Duration                    // maximum duration waiting for a connection
+	minCap             int                              // minimum number of connections
 	maxCap             int                              // maximum number of connections
-	openingConnections int                              // open ing connection
+	maxStreams         int                              // in-flight calls threshold of a shared connection
+	maxIdle            int                              // maximum number of idle connections
+	openingConnections int                              // open connections including the ones being created
+	leased             int                              // leased connections
+	closed             bool
+
+	waitCount    int64
+	waitDuration time.Duration
+	created      int64
+	closedCount  int64
+	pingFailed   int64
 }
 
 // Conn connection of grpc
 type Conn struct {
-	c *grpc.ClientConn
-	t time.Time
+	c        *grpc.ClientConn
+	t        time.Time // 最后一次释放回连接池的时间
+	lease    *Lease    // ModeLease 租用中的租用
+	inflight int       // 进行中的调用数
+	removed  bool      // 已经从连接池移除
+	draining bool      // 已经移除, 进行中的调用完成后关闭
+	created  time.Time // 创建的时间
+	cancel   context.CancelFunc
 }
 
 // New 初始化连接
@@ -57,21 +140,64 @@ func New(poolConfig *Config) (Pool, error) {
 	if poolConfig.Factory == nil {
 		return nil, errors.New("invalid factory func settings")
 	}
-	if poolConfig.Close == nil {
-		return nil, errors.New("invalid close func settings")
+	maxIdle := poolConfig.MaxIdle
+	if maxIdle == 0 {
+		maxIdle = poolConfig.MaxCap
+	}
+	if maxIdle < poolConfig.InitialCap || maxIdle > poolConfig.MaxCap {
+		return nil, errors.New("max idle must be between init cap and max cap")
+	}
+	if poolConfig.Mode < ModeLease || poolConfig.Mode > ModeP2C {
+		return nil, errors.New("invalid mode settings")
+	}
+	maxStreams := poolConfig.MaxStreams
+	if maxStreams <= 0 {
+		maxStreams = DefaultMaxStreams
+	}
+	closeFunc := poolConfig.Close
+	if closeFunc == nil {
+		closeFunc = func(conn *grpc.ClientConn) error {
+			return conn.Close()
+		}
 	}
 
-	c := &channelPool{
-		connections:        make(chan *Conn, poolConfig.MaxCap),
-		factory:            poolConfig.Factory,
-		close:              poolConfig.Close,
-		idleTimeout:        poolConfig.IdleTimeout,
-		maxCap:             poolConfig.MaxCap,
-		openingConnections: poolConfig.InitialCap,
+	ping := poolConfig.Ping
+	if ping == nil {
+		ping = HealthCheck("", DefaultPingTimeout)
+	}
+	checkInterval := poolConfig.CheckInterval
+	if checkInterval == 0 {
+		checkInterval = DefaultCheckInterval
+	}
+	failureGrace := poolConfig.FailureGrace
+	if failureGrace <= 0 {
+		failureGrace = DefaultFailureGrace
 	}
 
-	if poolConfig.Ping != nil {
-		c.ping = poolConfig.Ping
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &channelPool{
+		ctx:          ctx,
+		cancel:       cancel,
+		name:         poolConfig.Name,
+		mode:         poolConfig.Mode,
+		conns:        make(map[*grpc.ClientConn]*Conn, poolConfig.MaxCap),
+		factory:      poolConfig.Factory,
+		close:        closeFunc,
+		ping:         ping,
+		pingOnGet:    poolConfig.Ping != nil,
+		idleTimeout:  poolConfig.IdleTimeout,
+		failureGrace: failureGrace,
+		waitTimeout:  poolConfig.WaitTimeout,
+		minCap:       poolConfig.InitialCap,
+		maxCap:       poolConfig.MaxCap,
+		maxStreams:   maxStreams,
+		maxIdle:      maxIdle,
+	}
+	if c.name != "" {
+		if err := register(c.name, c); err != nil {
+			cancel()
+			return nil, err
+		}
 	}
 
 	for i := 0; i < poolConfig.InitialCap; i++ {
@@ -80,103 +206,403 @@ func New(poolConfig *Config) (Pool, error) {
 			c.Release()
 			return nil, fmt.Errorf("factory is not able to fill the pool: %s", err)
 		}
-		c.connections <- &Conn{c: connection, t: time.Now()}
+		c.mu.Lock()
+		c.openingConnections++
+		c.add(&Conn{c: connection, t: time.Now()})
+		c.mu.Unlock()
+	}
+	if checkInterval > 0 {
+		c.wg.Add(1)
+		go c.keep(checkInterval)
 	}
 
 	return c, nil
 }
 
-// getConnection get all connection in lock
-func (c *channelPool) getConnection() chan *Conn {
-	c.mu.Lock()
-	connections := c.connections
-	c.mu.Unlock()
-	return connections
-}
-
-// Get fetch a connection from pool
-func (c *channelPool) Get() (*grpc.ClientConn, error) {
-	connections := c.getConnection()
-	if connections == nil {
-		return nil, ErrClosed
+// Get lease a connection of the pool for one RPC, a new connection is created while the pool
+// has less than MaxCap connections, otherwise Get waits for a released connection until ctx
+// is done or WaitTimeout and returns ErrMaxActiveConnReached.
+//
+// With the shared modes Get never waits: a new connection is created only when every
+// connection has MaxStreams in-flight calls, beyond MaxCap the least loaded one is shared.
+func (c *channelPool) Get(ctx context.Context) (*Lease, error) {
+	if c.mode != ModeLease {
+		return c.getShared()
 	}
-	for {
-		select {
-		case wrapConn := <-connections:
-			if wrapConn == nil {
-				// return nil, ErrClosed
-				continue
-			}
-			// whether the timeout occurs, and discard the timeout
-			if timeout := c.idleTimeout; timeout > 0 {
-				if wrapConn.t.Add(timeout).Before(time.Now()) {
-					// close connect once timeout
-					_ = c.Close(wrapConn.c)
-					continue
-				}
+	var waitStart time.Time
+	defer func() {
+		if !waitStart.IsZero() {
+			waited := time.Since(waitStart)
+			c.mu.Lock()
+			c.waitDuration += waited
+			c.mu.Unlock()
+			if c.name != "" {
+				WaitDurationHistogram.WithLabelValues(c.name).Observe(waited.Seconds())
 			}
+		}
+	}()
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrClosed
+		}
+		if lease := c.popIdle(); lease != nil {
+			c.mu.Unlock()
 			// whether it is invalid. If it is invalid, discard it
 			// If the user does not set the ping method, do not check
-			// 如果设置了ping方法在调用之前检查一下这个连接
-			if c.ping != nil {
-				if err := c.Ping(wrapConn.c); err != nil {
-					_ = c.Close(wrapConn.c)
+			// 如果设置了ping方法在租用之前检查一下这个连接
+			if c.pingOnGet {
+				if err := c.Ping(lease.conn.c); err != nil {
+					c.mu.Lock()
+					c.pingFailed++
+					c.mu.Unlock()
+					c.put(lease.conn, true)
 					continue
 				}
 			}
-			// append used conn to the end
-			c.connections <- wrapConn
-			return wrapConn.c, nil
-		default:
-			c.mu.Lock()
-			if c.factory == nil {
-				c.mu.Unlock()
-				return nil, ErrClosed
-			}
-			conn, err := c.factory()
-			if err != nil {
-				c.mu.Unlock()
-				return nil, err
-			}
+			return lease, nil
+		}
+		if c.openingConnections < c.maxCap {
 			c.openingConnections++
 			c.mu.Unlock()
-			// add to buff
-			c.connections <- &Conn{c: conn, t: time.Now()}
-			return conn, nil
+			return c.open()
+		}
+		// 连接数达到上限, 等待其他连接释放
+		if waitStart.IsZero() {
+			waitStart = time.Now()
+			c.waitCount++
+			if c.waitTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.waitTimeout)
+				defer cancel()
+			}
+		}
+		wait := make(chan struct{}, 1)
+		c.waiters = append(c.waiters, wait)
+		c.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.removeWaiter(wait)
+			c.mu.Unlock()
+			return nil, errors.Wrap(ErrMaxActiveConnReached, ctx.Err().Error())
+		}
+	}
+}
+
+// popIdle leases the most recently released idle connection, the ones idle for longer than
+// idleTimeout are closed. The lock must be held.
+func (c *channelPool) popIdle() *Lease {
+	// whether the timeout occurs, and discard the timeout
+	for len(c.idle) > 0 && c.idleTimeout > 0 && c.idle[0].t.Add(c.idleTimeout).Before(time.Now()) {
+		wrapConn := c.idle[0]
+		c.remove(wrapConn)
+		// close connect once timeout
+		go func() { _ = c.close(wrapConn.c) }()
+	}
+	if len(c.idle) == 0 {
+		return nil
+	}
+	wrapConn := c.idle[len(c.idle)-1]
+	c.idle = c.idle[:len(c.idle)-1]
+	return c.lease(wrapConn)
+}
+
+// getShared leases a shared connection for one RPC.
+func (c *channelPool) getShared() (*Lease, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	for _, expired := range c.shrink() {
+		go func() { _ = c.close(expired.c) }()
+	}
+	wrapConn := c.pick()
+	// 所有连接进行中的调用都达到阈值时扩容
+	if (wrapConn == nil || wrapConn.inflight >= c.maxStreams) && c.openingConnections < c.maxCap {
+		c.openingConnections++
+		c.mu.Unlock()
+		lease, err := c.open()
+		if err == nil || wrapConn == nil {
+			return lease, err
+		}
+		// 创建连接失败时仍然使用已有的连接
+		c.mu.Lock()
+		if wrapConn.removed {
+			c.mu.Unlock()
+			return nil, err
 		}
 	}
+	if wrapConn == nil {
+		c.mu.Unlock()
+		return nil, ErrMaxActiveConnReached
+	}
+	lease := c.lease(wrapConn)
+	c.mu.Unlock()
+	return lease, nil
 }
 
-// Put add the connection back in the pool
+// pick returns the least loaded shared connection for ModeLeastStreams, the less loaded of
+// two random ones for ModeP2C, nil if there is none. The lock must be held.
+func (c *channelPool) pick() *Conn {
+	switch {
+	case len(c.shared) == 0:
+		return nil
+	case c.mode == ModeP2C && len(c.shared) > 1:
+		i := rand.Intn(len(c.shared))
+		j := rand.Intn(len(c.shared) - 1)
+		if j >= i {
+			j++
+		}
+		first, second := c.shared[i], c.shared[j]
+		// 两个连接都达到阈值时检查是否所有连接都达到阈值
+		if first.inflight >= c.maxStreams && second.inflight >= c.maxStreams {
+			return c.leastLoaded()
+		}
+		if second.inflight < first.inflight {
+			return second
+		}
+		return first
+	default:
+		return c.leastLoaded()
+	}
+}
+
+func (c *channelPool) leastLoaded() *Conn {
+	least := c.shared[0]
+	for _, wrapConn := range c.shared[1:] {
+		if wrapConn.inflight < least.inflight {
+			least = wrapConn
+		}
+	}
+	return least
+}
+
+// shrink removes the shared connections without in-flight calls for longer than idleTimeout
+// while the pool has more than minCap connections, the caller closes them. The lock must
+// be held.
+func (c *channelPool) shrink() []*Conn {
+	if c.idleTimeout <= 0 {
+		return nil
+	}
+	var expired []*Conn
+	for _, wrapConn := range append([]*Conn(nil), c.shared...) {
+		if c.openingConnections <= c.minCap {
+			break
+		}
+		if wrapConn.inflight == 0 && wrapConn.t.Add(c.idleTimeout).Before(time.Now()) {
+			c.remove(wrapConn)
+			expired = append(expired, wrapConn)
+		}
+	}
+	return expired
+}
+
+// open creates a leased connection, the slot of openingConnections is already taken.
+func (c *channelPool) open() (*Lease, error) {
+	conn, err := c.factory()
+	c.mu.Lock()
+	if err != nil {
+		c.openingConnections--
+		c.notify()
+		c.mu.Unlock()
+		return nil, err
+	}
+	wrapConn := &Conn{c: conn, t: time.Now()}
+	c.add(wrapConn)
+	if c.mode == ModeLease {
+		// 新的连接直接租用, 不放入空闲连接
+		c.idle = c.idle[:len(c.idle)-1]
+	}
+	lease := c.lease(wrapConn)
+	if c.closed {
+		c.mu.Unlock()
+		c.put(wrapConn, true)
+		return nil, ErrClosed
+	}
+	c.mu.Unlock()
+	return lease, nil
+}
+
+// add adds an idle connection to the pool and watches its state, the slot of
+// openingConnections is already taken. The lock must be held.
+func (c *channelPool) add(wrapConn *Conn) {
+	var ctx context.Context
+	ctx, wrapConn.cancel = context.WithCancel(c.ctx)
+	c.wg.Add(1)
+	go c.watch(ctx, wrapConn)
+	wrapConn.created = time.Now()
+	c.created++
+	c.conns[wrapConn.c] = wrapConn
+	if c.mode == ModeLease {
+		c.idle = append(c.idle, wrapConn)
+	} else {
+		c.shared = append(c.shared, wrapConn)
+	}
+}
+
+// lease leases the connection, the lock must be held.
+func (c *channelPool) lease(wrapConn *Conn) *Lease {
+	lease := &Lease{pool: c, conn: wrapConn}
+	wrapConn.inflight++
+	if c.mode == ModeLease {
+		wrapConn.lease = lease
+		c.leased++
+	}
+	return lease
+}
+
+// put ends the lease of the connection, it goes back to the idle connections unless the pool
+// already has maxIdle idle connections or discard is set.
+func (c *channelPool) put(wrapConn *Conn, discard bool) {
+	c.release(wrapConn, discard, true)
+}
+
+// release ends the lease of the connection like put, touch sets its idle time to now, a
+// connection leased by the background check keeps its idle time so it still expires.
+func (c *channelPool) release(wrapConn *Conn, discard bool, touch bool) {
+	c.mu.Lock()
+	wrapConn.inflight--
+	if c.mode != ModeLease {
+		if touch {
+			wrapConn.t = time.Now()
+		}
+		expired := c.shrink()
+		if !wrapConn.removed && (discard || c.closed) {
+			c.remove(wrapConn)
+			wrapConn.draining = true
+		}
+		// 连接移除之后最后一个调用完成时关闭连接
+		if wrapConn.draining && wrapConn.inflight == 0 {
+			wrapConn.draining = false
+			expired = append(expired, wrapConn)
+		}
+		c.mu.Unlock()
+		for _, e := range expired {
+			_ = c.close(e.c)
+		}
+		return
+	}
+	wrapConn.lease = nil
+	c.leased--
+	if wrapConn.removed {
+		draining := wrapConn.draining
+		wrapConn.draining = false
+		c.mu.Unlock()
+		if draining {
+			_ = c.close(wrapConn.c)
+		}
+		return
+	}
+	if discard || c.closed || len(c.idle) >= c.maxIdle {
+		c.remove(wrapConn)
+		c.mu.Unlock()
+		_ = c.close(wrapConn.c)
+		return
+	}
+	if touch {
+		wrapConn.t = time.Now()
+	}
+	c.idle = insertIdle(c.idle, wrapConn)
+	c.notify()
+	c.mu.Unlock()
+}
+
+// insertIdle inserts the connection in the idle connections ordered by idle time.
+func insertIdle(idle []*Conn, wrapConn *Conn) []*Conn {
+	i := len(idle)
+	for i > 0 && idle[i-1].t.After(wrapConn.t) {
+		i--
+	}
+	idle = append(idle, nil)
+	copy(idle[i+1:], idle[i:])
+	idle[i] = wrapConn
+	return idle
+}
+
+// remove removes the connection of the pool, the caller closes it. The lock must be held.
+func (c *channelPool) remove(wrapConn *Conn) {
+	wrapConn.removed = true
+	if wrapConn.cancel != nil {
+		wrapConn.cancel()
+	}
+	c.idle = without(c.idle, wrapConn)
+	c.shared = without(c.shared, wrapConn)
+	delete(c.conns, wrapConn.c)
+	c.openingConnections--
+	c.closedCount++
+	c.notify()
+}
+
+func without(conns []*Conn, wrapConn *Conn) []*Conn {
+	for i, conn := range conns {
+		if conn == wrapConn {
+			return append(conns[:i], conns[i+1:]...)
+		}
+	}
+	return conns
+}
+
+// notify wakes up the first waiting Get, the lock must be held.
+func (c *channelPool) notify() {
+	if len(c.waiters) == 0 {
+		return
+	}
+	wait := c.waiters[0]
+	c.waiters = c.waiters[1:]
+	wait <- struct{}{}
+}
+
+// removeWaiter removes a Get giving up, a wake up it already received is passed on to the
+// next waiting Get. The lock must be held.
+func (c *channelPool) removeWaiter(wait chan struct{}) {
+	for i, w := range c.waiters {
+		if w == wait {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+	select {
+	case <-wait:
+		c.notify()
+	default:
+	}
+}
+
+// Put ends the lease of the connection, only the connections of ModeLease are leased
+// exclusively and may be put back without their Lease.
 func (c *channelPool) Put(conn *grpc.ClientConn) error {
 	if conn == nil {
 		return errors.New("connection is nil. rejecting")
 	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.connections == nil {
-		_ = c.Close(conn)
-		return errors.New("connection is nil")
+	wrapConn, ok := c.conns[conn]
+	var lease *Lease
+	if ok {
+		lease = wrapConn.lease
 	}
-	if len(c.connections) >= c.maxCap {
-		_ = c.Close(conn)
-		return nil
+	c.mu.Unlock()
+	if lease == nil {
+		return errors.New("connection is not leased")
 	}
-	c.connections <- &Conn{c: conn, t: time.Now()}
+	lease.Release()
 	return nil
 }
 
-// Close connection close
+// Close connection close, a leased connection is removed of the pool at once and its lease
+// ends as usual.
 func (c *channelPool) Close(conn *grpc.ClientConn) error {
 	if conn == nil {
 		return errors.New("connection is nil. rejecting")
 	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.close == nil {
-		return nil
+	if wrapConn, ok := c.conns[conn]; ok {
+		c.remove(wrapConn)
 	}
-	c.openingConnections--
+	c.mu.Unlock()
 	return c.close(conn)
 }
 
@@ -188,25 +614,87 @@ func (c *channelPool) Ping(conn *grpc.ClientConn) error {
 	return c.ping(conn)
 }
 
-// Release all connections in the connection pool drop
+// Invoke leases a connection for the unary call
+func (c *channelPool) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	lease, err := c.Get(ctx)
+	if err != nil {
+		return err
+	}
+	return lease.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream leases a connection for the stream
+func (c *channelPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	lease, err := c.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lease.NewStream(ctx, desc, method, opts...)
+}
+
+// Release all connections in the connection pool drop and stop the background checks, the
+// leased connections are closed when their last lease ends.
 func (c *channelPool) Release() {
 	c.mu.Lock()
-	connections := c.connections
-	c.connections = nil
-	c.factory = nil
-	c.ping = nil
-	c.close = nil
-	c.mu.Unlock()
-	if connections == nil {
+	if c.closed {
+		c.mu.Unlock()
 		return
 	}
-	close(connections)
-	for wrapConn := range connections {
+	c.closed = true
+	var idle []*Conn
+	for _, wrapConn := range c.conns {
+		if wrapConn.inflight == 0 {
+			idle = append(idle, wrapConn)
+		}
+	}
+	for _, wrapConn := range idle {
+		c.remove(wrapConn)
+	}
+	// 唤醒所有等待的请求返回ErrClosed
+	for len(c.waiters) > 0 {
+		c.notify()
+	}
+	c.mu.Unlock()
+	c.cancel()
+	if c.name != "" {
+		unregister(c.name, c)
+	}
+	for _, wrapConn := range idle {
 		_ = c.close(wrapConn.c)
 	}
+	c.wg.Wait()
 }
 
 // Len gen connection length
 func (c *channelPool) Len() int {
-	return len(c.getConnection())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.openingConnections
+}
+
+// Stats returns the statistics of the pool
+func (c *channelPool) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var idle, leased, inflight int
+	for _, wrapConn := range c.conns {
+		if wrapConn.inflight == 0 {
+			idle++
+		} else {
+			leased++
+			inflight += wrapConn.inflight
+		}
+	}
+	return Stats{
+		Open:         c.openingConnections,
+		Idle:         idle,
+		Leased:       leased,
+		InFlight:     inflight,
+		Waiting:      len(c.waiters),
+		WaitCount:    c.waitCount,
+		WaitDuration: c.waitDuration,
+		Created:      c.created,
+		Closed:       c.closedCount,
+		PingFailed:   c.pingFailed,
+	}
 }
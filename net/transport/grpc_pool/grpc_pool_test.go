@@ -2,49 +2,454 @@ package grpc_pool
 
 import (
 	"context"
+	"errors"
+	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	commonErrors "github.com/weiqiangxu/micro_project/common-config/errors"
 	netGrpc "github.com/weiqiangxu/micro_project/net/transport/grpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestNew(t *testing.T) {
-	type args struct {
-		poolConfig *Config
+	addr := startHealthServer(t)
+	factory := func() (*grpc.ClientConn, error) {
+		return grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    Pool
-		wantErr bool
+		name     string
+		config   *Config
+		wantOpen int
+		wantErr  bool
 	}{
-		{
-			name: "test",
-			args: args{
-				poolConfig: &Config{
-					InitialCap: 5,
-					MaxCap:     10,
-					Factory: func() (*grpc.ClientConn, error) {
-						return netGrpc.Dial(context.Background())
-					},
-					Close:       nil,
-					Ping:        nil,
-					IdleTimeout: 0,
-				},
-			},
-			want:    nil,
-			wantErr: false,
-		},
+		{"initial connections", &Config{InitialCap: 5, MaxCap: 10, Factory: factory}, 5, false},
+		{"invalid capacity", &Config{InitialCap: 5, MaxCap: 2, Factory: factory}, 0, true},
+		{"without factory", &Config{InitialCap: 1, MaxCap: 2}, 0, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := New(tt.args.poolConfig)
+			got, err := New(tt.config)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
 				return
 			}
-			clientConn, _ := got.Get()
-			t.Logf("%+v", clientConn)
+			defer got.Release()
+			if stats := got.Stats(); stats.Open != tt.wantOpen || stats.Idle != tt.wantOpen {
+				t.Fatalf("Stats() = %+v, want %d open idle connections", stats, tt.wantOpen)
+			}
+			lease, err := got.Get(context.Background())
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if _, err := healthpb.NewHealthClient(lease).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
 		})
 	}
 }
+
+// startHealthServer starts a gRPC server with the health service and returns its address.
+func startHealthServer(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// newTestPool creates a pool of connections to addr.
+func newTestPool(t *testing.T, addr string, config Config) Pool {
+	t.Helper()
+	config.Factory = func() (*grpc.ClientConn, error) {
+		return grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	p, err := New(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Release)
+	return p
+}
+
+func TestChannelPool_Get(t *testing.T) {
+	p := newTestPool(t, startHealthServer(t), Config{InitialCap: 1, MaxCap: 2, WaitTimeout: 50 * time.Millisecond})
+	first, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ClientConn() == second.ClientConn() {
+		t.Fatal("a leased connection is leased again")
+	}
+	if _, err := p.Get(context.Background()); !errors.Is(err, ErrMaxActiveConnReached) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrMaxActiveConnReached)
+	}
+	stats := p.Stats()
+	if stats.Open != 2 || stats.Leased != 2 || stats.Idle != 0 || stats.WaitCount != 1 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+
+	// 释放的连接唤醒等待的请求
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		first.Release()
+		first.Release()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	third, err := p.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.ClientConn() != first.ClientConn() {
+		t.Fatal("the released connection is not leased again")
+	}
+	if stats := p.Stats(); stats.Leased != 2 {
+		t.Fatalf("Stats().Leased = %d, want 2", stats.Leased)
+	}
+}
+
+func TestChannelPool_Invoke(t *testing.T) {
+	p := newTestPool(t, startHealthServer(t), Config{InitialCap: 1, MaxCap: 2})
+	client := healthpb.NewHealthClient(p)
+	for i := 0; i < 3; i++ {
+		if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 调用完成时释放连接, 串行的调用复用同一个连接
+	if stats := p.Stats(); stats.Open != 1 || stats.Leased != 0 || stats.Idle != 1 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := p.Stats(); stats.Leased != 1 {
+		t.Fatalf("Stats().Leased = %d during the stream, want 1", stats.Leased)
+	}
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for p.Stats().Leased != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the lease of the stream is not released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChannelPool_CircuitBreaker(t *testing.T) {
+	addr := startHealthServer(t)
+	breaker := netGrpc.DefaultBreakerConfig()
+	breaker.MinRequests = 2
+	breaker.FailureCodes = []codes.Code{codes.NotFound}
+	p, err := New(&Config{
+		InitialCap:  1,
+		MaxCap:      2,
+		WaitTimeout: 50 * time.Millisecond,
+		Factory: func() (*grpc.ClientConn, error) {
+			return netGrpc.Dial(context.Background(), netGrpc.WithEndpoint(addr), netGrpc.WithInSecure(true),
+				netGrpc.WithRetry("*", netGrpc.DefaultRetryPolicy()), netGrpc.WithCircuitBreaker(breaker))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release()
+	client := healthpb.NewHealthClient(p)
+	// 健康检查服务对未知的服务返回 NotFound, 两次失败后熔断器打开
+	for i := 0; i < 5; i++ {
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+		if i >= 2 && !errors.Is(err, commonErrors.ErrBreakerOpen) {
+			t.Fatalf("call %d error = %v, want %v", i, err, commonErrors.ErrBreakerOpen)
+		}
+	}
+	// 熔断拒绝的调用同样释放连接, 连接池没有被耗尽
+	if stats := p.Stats(); stats.Leased != 0 || stats.Open != 1 {
+		t.Fatalf("Stats() = %+v, want the rejected calls released", stats)
+	}
+}
+
+func TestChannelPool_MaxIdle(t *testing.T) {
+	p := newTestPool(t, startHealthServer(t), Config{InitialCap: 1, MaxCap: 3, MaxIdle: 1})
+	var leases []*Lease
+	for i := 0; i < 3; i++ {
+		lease, err := p.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		leases = append(leases, lease)
+	}
+	for _, lease := range leases {
+		lease.Release()
+	}
+	stats := p.Stats()
+	if stats.Open != 1 || stats.Idle != 1 || stats.Created != 3 || stats.Closed != 2 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+
+	p.Release()
+	if _, err := p.Get(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrClosed)
+	}
+	if n := p.Len(); n != 0 {
+		t.Fatalf("Len() = %d after Release, want 0", n)
+	}
+}
+
+func TestChannelPool_Shared(t *testing.T) {
+	addr := startHealthServer(t)
+	for _, mode := range []Mode{ModeLeastStreams, ModeP2C} {
+		t.Run(mode.String(), func(t *testing.T) {
+			p := newTestPool(t, addr, Config{Mode: mode, InitialCap: 1, MaxCap: 2, MaxStreams: 2, IdleTimeout: 20 * time.Millisecond})
+			loads := make(map[*grpc.ClientConn]int)
+			var leases []*Lease
+			for i := 0; i < 5; i++ {
+				lease, err := p.Get(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				loads[lease.ClientConn()]++
+				leases = append(leases, lease)
+				// 第一个连接达到阈值之前不扩容
+				if i < 2 && len(loads) != 1 {
+					t.Fatalf("%d connections for %d calls, want 1", len(loads), i+1)
+				}
+			}
+			// 扩容到MaxCap之后共享进行中的调用最少的连接
+			if len(loads) != 2 {
+				t.Fatalf("%d connections, want 2", len(loads))
+			}
+			for conn, load := range loads {
+				if load < 2 || load > 3 {
+					t.Fatalf("%s has %d calls, want 2 or 3", conn.Target(), load)
+				}
+			}
+			stats := p.Stats()
+			if stats.Open != 2 || stats.Leased != 2 || stats.InFlight != 5 {
+				t.Fatalf("Stats() = %+v", stats)
+			}
+
+			for _, lease := range leases {
+				lease.Release()
+			}
+			if stats := p.Stats(); stats.Idle != 2 || stats.InFlight != 0 {
+				t.Fatalf("Stats() = %+v", stats)
+			}
+			// 空闲超过IdleTimeout的连接缩容到InitialCap
+			time.Sleep(30 * time.Millisecond)
+			lease, err := p.Get(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lease.Release()
+			if stats := p.Stats(); stats.Open != 1 || stats.Closed != 1 {
+				t.Fatalf("Stats() = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ping := HealthCheck("", time.Second)
+	if err := ping(conn); err != nil {
+		t.Fatalf("ping of a serving server = %v", err)
+	}
+	healthServer.Shutdown()
+	if err := ping(conn); err == nil {
+		t.Fatal("ping of a server shutting down succeeded")
+	}
+}
+
+func TestChannelPool_Keeper(t *testing.T) {
+	var pings, failures atomic.Int32
+	failures.Store(1)
+	p := newTestPool(t, startHealthServer(t), Config{
+		InitialCap:    2,
+		MaxCap:        2,
+		CheckInterval: 10 * time.Millisecond,
+		Ping: func(conn *grpc.ClientConn) error {
+			pings.Add(1)
+			if failures.Add(-1) >= 0 {
+				return errors.New("ping failed")
+			}
+			return nil
+		},
+	})
+	// 检查失败的连接被替换, 补充到InitialCap
+	deadline := time.Now().Add(time.Second)
+	for {
+		stats := p.Stats()
+		if stats.PingFailed == 1 && stats.Created == 3 && stats.Closed == 1 && stats.Open == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats() = %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	p.Release()
+	n := pings.Load()
+	time.Sleep(30 * time.Millisecond)
+	if pings.Load() != n {
+		t.Fatal("the keeper is still running after Release")
+	}
+}
+
+// slowHealthServer answers the health checks after a delay.
+type slowHealthServer struct {
+	*health.Server
+	delay time.Duration
+}
+
+func (s slowHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	time.Sleep(s.delay)
+	return s.Server.Check(ctx, req)
+}
+
+func TestChannelPool_KeeperGet(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, slowHealthServer{Server: health.NewServer(), delay: 50 * time.Millisecond})
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+	p := newTestPool(t, lis.Addr().String(), Config{
+		InitialCap:    2,
+		MaxCap:        2,
+		CheckInterval: 10 * time.Millisecond,
+		WaitTimeout:   20 * time.Millisecond,
+	})
+	// 后台检查一次只租用一个连接, 检查期间其他连接仍然可以租用
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		lease, err := p.Get(context.Background())
+		if err != nil {
+			t.Fatalf("Get() error = %v during the background check", err)
+		}
+		lease.Release()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChannelPool_KeeperIdleTimeout(t *testing.T) {
+	p := newTestPool(t, startHealthServer(t), Config{
+		InitialCap:    1,
+		MaxCap:        3,
+		IdleTimeout:   300 * time.Millisecond,
+		CheckInterval: 20 * time.Millisecond,
+	})
+	var leases []*Lease
+	for i := 0; i < 3; i++ {
+		lease, err := p.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		leases = append(leases, lease)
+	}
+	for _, lease := range leases {
+		lease.Release()
+	}
+	// 后台检查不刷新空闲时间, 空闲超时的连接被关闭, 剩下InitialCap个连接
+	deadline := time.Now().Add(1500 * time.Millisecond)
+	for {
+		stats := p.Stats()
+		if stats.Open == 1 && stats.Closed >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats() = %+v, want the idle connections closed", stats)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestChannelPool_Watch(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go func() { _ = s.Serve(lis) }()
+	p := newTestPool(t, lis.Addr().String(), Config{InitialCap: 2, MaxCap: 2, CheckInterval: -1, FailureGrace: 300 * time.Millisecond})
+
+	// 服务端停止后连接失败, gRPC重新连接期间保留在连接池中
+	s.Stop()
+	stopped := time.Now()
+	deadline := stopped.Add(5 * time.Second)
+	for {
+		stats := p.Stats()
+		if stats.Open == 0 && stats.Closed == 2 {
+			break
+		}
+		if stats.Closed > 0 && time.Since(stopped) < 300*time.Millisecond {
+			t.Fatalf("Stats() = %+v, a failed connection is retired before FailureGrace", stats)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats() = %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChannelPool_WatchShutdown(t *testing.T) {
+	p := newTestPool(t, startHealthServer(t), Config{InitialCap: 2, MaxCap: 2, CheckInterval: -1})
+	lease, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 关闭的连接立即从连接池移除
+	_ = lease.ClientConn().Close()
+	lease.Release()
+	deadline := time.Now().Add(time.Second)
+	for {
+		stats := p.Stats()
+		if stats.Open == 1 && stats.Closed == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Stats() = %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,189 @@
+package grpc_pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// DefaultCheckInterval 后台检查连接的默认间隔
+const DefaultCheckInterval = 10 * time.Second
+
+// DefaultPingTimeout 默认健康检查的超时时间
+const DefaultPingTimeout = time.Second
+
+// DefaultFailureGrace 连接失败后等待gRPC重新连接的默认时间, 超过后替换连接
+const DefaultFailureGrace = 30 * time.Second
+
+// HealthCheck 使用标准的健康检查服务 grpc.health.v1.Health 检查连接, service为空时检查整个服务端,
+// 服务端不是 SERVING 状态时返回错误
+func HealthCheck(service string, timeout time.Duration) func(*grpc.ClientConn) error {
+	return func(conn *grpc.ClientConn) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return errors.Errorf("health check of %s is %s", conn.Target(), resp.GetStatus())
+		}
+		return nil
+	}
+}
+
+// watch follows the state transitions of the connection until it is removed of the pool,
+// an idle connection is connected again to keep it warm. A failed connection reconnects with
+// the backoff of gRPC and is retired once it has failed for longer than failureGrace without
+// being ready again, a shut down one is retired at once.
+func (c *channelPool) watch(ctx context.Context, wrapConn *Conn) {
+	defer c.wg.Done()
+	var failing time.Time // 上次就绪之后第一次失败的时间
+	for {
+		state := wrapConn.c.GetState()
+		switch state {
+		case connectivity.Idle:
+			wrapConn.c.Connect()
+		case connectivity.Ready:
+			failing = time.Time{}
+		case connectivity.TransientFailure:
+			if failing.IsZero() {
+				failing = time.Now()
+			}
+		case connectivity.Shutdown:
+			c.retire(wrapConn)
+			return
+		}
+		if failing.IsZero() {
+			if !wrapConn.c.WaitForStateChange(ctx, state) {
+				return
+			}
+			continue
+		}
+		remaining := c.failureGrace - time.Since(failing)
+		if remaining <= 0 {
+			// 连接失败超过宽限时间时替换连接, 后台检查补充到 InitialCap
+			c.retire(wrapConn)
+			return
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, remaining)
+		wrapConn.c.WaitForStateChange(waitCtx, state)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// retire removes the failed connection of the pool, it is closed once its in-flight calls
+// are done.
+func (c *channelPool) retire(wrapConn *Conn) {
+	c.mu.Lock()
+	if wrapConn.removed {
+		c.mu.Unlock()
+		return
+	}
+	c.remove(wrapConn)
+	closeNow := wrapConn.inflight == 0
+	wrapConn.draining = !closeNow
+	c.mu.Unlock()
+	if closeNow {
+		_ = c.close(wrapConn.c)
+	}
+}
+
+// keep checks the connections every interval until the pool is released.
+func (c *channelPool) keep(interval time.Duration) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.check()
+		}
+	}
+}
+
+// check closes the expired idle connections, pings the other idle ones one by one to replace
+// the failed ones and fills the pool up to minCap.
+func (c *channelPool) check() {
+	c.mu.Lock()
+	var expired []*Conn
+	if c.mode == ModeLease {
+		for len(c.idle) > 0 && c.idleTimeout > 0 && c.idle[0].t.Add(c.idleTimeout).Before(time.Now()) {
+			expired = append(expired, c.idle[0])
+			c.remove(c.idle[0])
+		}
+	} else {
+		expired = c.shrink()
+	}
+	var candidates []*Conn
+	for _, wrapConn := range c.conns {
+		candidates = append(candidates, wrapConn)
+	}
+	c.mu.Unlock()
+	for _, wrapConn := range expired {
+		_ = c.close(wrapConn.c)
+	}
+	for _, wrapConn := range candidates {
+		c.checkConn(wrapConn)
+	}
+	c.fill()
+}
+
+// checkConn pings the connection if it is still idle, it is leased during the ping so no
+// call or shrink takes it, and only one connection is taken out of the pool at a time.
+func (c *channelPool) checkConn(wrapConn *Conn) {
+	c.mu.Lock()
+	if wrapConn.removed || wrapConn.inflight > 0 || c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.idle = without(c.idle, wrapConn)
+	c.lease(wrapConn)
+	c.mu.Unlock()
+	if err := c.ping(wrapConn.c); err != nil {
+		c.mu.Lock()
+		c.pingFailed++
+		c.mu.Unlock()
+		c.put(wrapConn, true)
+		return
+	}
+	// 检查不算使用, 保留空闲的时间以便空闲超时的连接被关闭
+	c.release(wrapConn, false, false)
+}
+
+// fill creates idle connections until the pool has minCap connections.
+func (c *channelPool) fill() {
+	for {
+		c.mu.Lock()
+		if c.closed || c.openingConnections >= c.minCap {
+			c.mu.Unlock()
+			return
+		}
+		c.openingConnections++
+		c.mu.Unlock()
+		conn, err := c.factory()
+		c.mu.Lock()
+		if err != nil || c.closed {
+			c.openingConnections--
+			c.notify()
+			c.mu.Unlock()
+			if err == nil {
+				_ = c.close(conn)
+			}
+			return
+		}
+		wrapConn := &Conn{c: conn, t: time.Now()}
+		c.add(wrapConn)
+		c.notify()
+		c.mu.Unlock()
+	}
+}
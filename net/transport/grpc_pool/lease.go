@@ -0,0 +1,45 @@
+package grpc_pool
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Lease 一次RPC调用对连接的租用
+//
+// 通过 Lease 发起的一元调用在返回时释放租用, 流式调用在流结束时释放租用, 连接上的拦截器(如熔断、重试)
+// 拒绝或重试调用都在租用期间内, 也可以调用 Release 手动释放
+type Lease struct {
+	pool *channelPool
+	conn *Conn
+	once sync.Once
+}
+
+// ClientConn 租用的连接
+func (l *Lease) ClientConn() *grpc.ClientConn {
+	return l.conn.c
+}
+
+// Invoke 在租用的连接上发起一元调用, 调用返回后释放租用
+func (l *Lease) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	defer l.Release()
+	return l.conn.c.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream 在租用的连接上发起流式调用, 流结束(收到EOF、出错或ctx取消)或创建失败时释放租用
+func (l *Lease) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := l.conn.c.NewStream(ctx, desc, method, append(opts, grpc.OnFinish(func(error) { l.Release() }))...)
+	if err != nil {
+		l.Release()
+	}
+	return stream, err
+}
+
+// Release 释放租用, 多次调用只释放一次
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		l.pool.put(l.conn, false)
+	})
+}
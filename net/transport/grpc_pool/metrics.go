@@ -0,0 +1,152 @@
+package grpc_pool
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	PoolLabel = "pool"
+	// DebugPath 调试接口的默认路径
+	DebugPath = "/debug/grpc_pool"
+)
+
+var (
+	poolsMu sync.RWMutex
+	// pools 有名称的连接池, 用于指标和调试接口
+	pools = make(map[string]*channelPool)
+)
+
+// register registers the named pool for the metrics and the debug handler.
+func register(name string, c *channelPool) error {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	if _, ok := pools[name]; ok {
+		return errors.Errorf("pool name %q is already used", name)
+	}
+	pools[name] = c
+	return nil
+}
+
+func unregister(name string, c *channelPool) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	if pools[name] == c {
+		delete(pools, name)
+	}
+}
+
+// namedPools returns the named pools sorted by name.
+func namedPools() []*channelPool {
+	poolsMu.RLock()
+	defer poolsMu.RUnlock()
+	list := make([]*channelPool, 0, len(pools))
+	for _, c := range pools {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// WaitDurationHistogram 1.连接数达到上限时等待连接的时长
+var WaitDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "grpc_pool_wait_duration_seconds",
+	Help:    "GRPC连接池等待连接的时长",
+	Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+}, []string{PoolLabel})
+
+// Collector 2.连接池的连接数和统计, 采集时读取每个有名称的连接池的 Stats
+var Collector prometheus.Collector = statsCollector{}
+
+var (
+	openDesc       = prometheus.NewDesc("grpc_pool_open_connections", "GRPC连接池打开的连接数", []string{PoolLabel}, nil)
+	idleDesc       = prometheus.NewDesc("grpc_pool_idle_connections", "GRPC连接池空闲的连接数", []string{PoolLabel}, nil)
+	leasedDesc     = prometheus.NewDesc("grpc_pool_leased_connections", "GRPC连接池有进行中的调用的连接数", []string{PoolLabel}, nil)
+	inFlightDesc   = prometheus.NewDesc("grpc_pool_in_flight_calls", "GRPC连接池进行中的调用数", []string{PoolLabel}, nil)
+	waitingDesc    = prometheus.NewDesc("grpc_pool_waiting_requests", "GRPC连接池等待连接的请求数", []string{PoolLabel}, nil)
+	createdDesc    = prometheus.NewDesc("grpc_pool_created_connections_total", "GRPC连接池创建的连接总数", []string{PoolLabel}, nil)
+	closedDesc     = prometheus.NewDesc("grpc_pool_closed_connections_total", "GRPC连接池关闭的连接总数", []string{PoolLabel}, nil)
+	pingFailedDesc = prometheus.NewDesc("grpc_pool_ping_failures_total", "GRPC连接池检查连接失败的总次数", []string{PoolLabel}, nil)
+)
+
+type statsCollector struct{}
+
+func (statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{openDesc, idleDesc, leasedDesc, inFlightDesc, waitingDesc, createdDesc, closedDesc, pingFailedDesc} {
+		ch <- desc
+	}
+}
+
+func (statsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range namedPools() {
+		stats := c.Stats()
+		ch <- prometheus.MustNewConstMetric(openDesc, prometheus.GaugeValue, float64(stats.Open), c.name)
+		ch <- prometheus.MustNewConstMetric(idleDesc, prometheus.GaugeValue, float64(stats.Idle), c.name)
+		ch <- prometheus.MustNewConstMetric(leasedDesc, prometheus.GaugeValue, float64(stats.Leased), c.name)
+		ch <- prometheus.MustNewConstMetric(inFlightDesc, prometheus.GaugeValue, float64(stats.InFlight), c.name)
+		ch <- prometheus.MustNewConstMetric(waitingDesc, prometheus.GaugeValue, float64(stats.Waiting), c.name)
+		ch <- prometheus.MustNewConstMetric(createdDesc, prometheus.CounterValue, float64(stats.Created), c.name)
+		ch <- prometheus.MustNewConstMetric(closedDesc, prometheus.CounterValue, float64(stats.Closed), c.name)
+		ch <- prometheus.MustNewConstMetric(pingFailedDesc, prometheus.CounterValue, float64(stats.PingFailed), c.name)
+	}
+}
+
+// ConnInfo 连接池中一个连接的状态
+type ConnInfo struct {
+	Target   string `json:"target"`
+	State    string `json:"state"`
+	Age      string `json:"age"`
+	InFlight int    `json:"in_flight"`
+}
+
+// PoolInfo 调试接口返回的连接池状态
+type PoolInfo struct {
+	Name        string     `json:"name"`
+	Mode        string     `json:"mode"`
+	Stats       Stats      `json:"stats"`
+	Connections []ConnInfo `json:"connections"`
+}
+
+// DebugHandler 返回每个有名称的连接池的统计和连接, 如挂载到 transport/http.Server:
+// http.WithAdminHandler(grpc_pool.DebugPath, grpc_pool.DebugHandler())
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list := make([]PoolInfo, 0)
+		for _, c := range namedPools() {
+			list = append(list, PoolInfo{Name: c.name, Mode: c.mode.String(), Stats: c.Stats(), Connections: c.Connections()})
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(list)
+	})
+}
+
+// Connections returns the state of the open connections, the oldest first.
+func (c *channelPool) Connections() []ConnInfo {
+	c.mu.Lock()
+	conns := make([]*Conn, 0, len(c.conns))
+	for _, wrapConn := range c.conns {
+		conns = append(conns, wrapConn)
+	}
+	inflight := make(map[*Conn]int, len(conns))
+	for _, wrapConn := range conns {
+		inflight[wrapConn] = wrapConn.inflight
+	}
+	c.mu.Unlock()
+	sort.Slice(conns, func(i, j int) bool { return conns[i].created.Before(conns[j].created) })
+	infos := make([]ConnInfo, 0, len(conns))
+	for _, wrapConn := range conns {
+		infos = append(infos, ConnInfo{
+			Target:   wrapConn.c.Target(),
+			State:    wrapConn.c.GetState().String(),
+			Age:      time.Since(wrapConn.created).Round(time.Millisecond).String(),
+			InFlight: inflight[wrapConn],
+		})
+	}
+	return infos
+}
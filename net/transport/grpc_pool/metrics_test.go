@@ -0,0 +1,123 @@
+package grpc_pool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// gather returns the values of the metrics of the pool by name.
+func gather(t *testing.T, pool string) map[string]float64 {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(Collector, WaitDurationHistogram)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			if !hasPoolLabel(m, pool) {
+				continue
+			}
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				values[family.GetName()] = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				values[family.GetName()] = m.GetCounter().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				values[family.GetName()] = float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
+
+func hasPoolLabel(m *dto.Metric, pool string) bool {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == PoolLabel && label.GetValue() == pool {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCollector(t *testing.T) {
+	p := newTestPool(t, startHealthServer(t), Config{Name: "collector", InitialCap: 1, MaxCap: 1, WaitTimeout: 10 * time.Millisecond})
+	if _, err := New(&Config{Name: "collector", InitialCap: 1, MaxCap: 1, Factory: p.(*channelPool).factory}); err == nil {
+		t.Fatal("a second pool with the same name is created")
+	}
+	waits := gather(t, "collector")["grpc_pool_wait_duration_seconds"]
+	lease, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get(context.Background()); err == nil {
+		t.Fatal("Get() of an exhausted pool succeeded")
+	}
+	want := map[string]float64{
+		"grpc_pool_open_connections":          1,
+		"grpc_pool_idle_connections":          0,
+		"grpc_pool_leased_connections":        1,
+		"grpc_pool_in_flight_calls":           1,
+		"grpc_pool_waiting_requests":          0,
+		"grpc_pool_created_connections_total": 1,
+		"grpc_pool_closed_connections_total":  0,
+		"grpc_pool_ping_failures_total":       0,
+		"grpc_pool_wait_duration_seconds":     waits + 1,
+	}
+	got := gather(t, "collector")
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %v, want %v", name, got[name], value)
+		}
+	}
+	lease.Release()
+
+	p.Release()
+	if got := gather(t, "collector"); len(got) > 1 {
+		t.Fatalf("metrics of a released pool = %v", got)
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	addr := startHealthServer(t)
+	p := newTestPool(t, addr, Config{Name: "debug", Mode: ModeLeastStreams, InitialCap: 1, MaxCap: 2})
+	lease, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+
+	w := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, DebugPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, want %d", w.Code, http.StatusOK)
+	}
+	var list []PoolInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	var info *PoolInfo
+	for i := range list {
+		if list[i].Name == "debug" {
+			info = &list[i]
+		}
+	}
+	if info == nil {
+		t.Fatalf("pool debug not in %s", w.Body.String())
+	}
+	if info.Mode != "least_streams" || info.Stats.InFlight != 1 || len(info.Connections) != 1 {
+		t.Fatalf("info = %+v", info)
+	}
+	if conn := info.Connections[0]; conn.Target != addr || conn.InFlight != 1 || conn.State == "" || conn.Age == "" {
+		t.Fatalf("connection = %+v", conn)
+	}
+}
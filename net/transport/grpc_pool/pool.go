@@ -1,7 +1,9 @@
 package grpc_pool
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"google.golang.org/grpc"
 )
@@ -10,14 +12,51 @@ import (
 var ErrClosed = errors.New("pool is closed")
 
 // Pool 基本方法
+//
+// Pool 实现了 grpc.ClientConnInterface, 每次RPC调用租用一个连接, 调用完成后释放回连接池,
+// 可以直接用于创建客户端, 如 pbUser.NewLoginClient(pool)
 type Pool interface {
-	Get() (*grpc.ClientConn, error)
+	grpc.ClientConnInterface
 
+	// Get 租用一个连接, ModeLease 连接数达到上限时等待其他连接释放直到ctx结束
+	Get(ctx context.Context) (*Lease, error)
+
+	// Put 释放连接的租用
 	Put(*grpc.ClientConn) error
 
+	// Close 从连接池移除并关闭连接
 	Close(*grpc.ClientConn) error
 
 	Release()
 
 	Len() int
+
+	Stats() Stats
+
+	// Connections 打开的连接的状态
+	Connections() []ConnInfo
+}
+
+// Stats 连接池的统计
+type Stats struct {
+	// Open 打开的连接数, 包括正在创建的连接
+	Open int `json:"open"`
+	// Idle 空闲的连接数
+	Idle int `json:"idle"`
+	// Leased 有进行中的调用的连接数
+	Leased int `json:"leased"`
+	// InFlight 进行中的调用数
+	InFlight int `json:"in_flight"`
+	// Waiting 等待连接的请求数
+	Waiting int `json:"waiting"`
+	// WaitCount 等待过连接的请求总数
+	WaitCount int64 `json:"wait_count"`
+	// WaitDuration 等待连接的总时长
+	WaitDuration time.Duration `json:"wait_duration"`
+	// Created 创建的连接总数
+	Created int64 `json:"created"`
+	// Closed 关闭的连接总数
+	Closed int64 `json:"closed"`
+	// PingFailed 检查失败的总次数
+	PingFailed int64 `json:"ping_failed"`
 }
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+)
+
+const (
+	// AdminLogLevelPath is the path of the log level admin handler.
+	AdminLogLevelPath = "/admin/log/level"
+	// AdminTokenHeader is the header carrying the admin token besides Authorization: Bearer.
+	AdminTokenHeader = "X-Admin-Token"
+)
+
+// LogLevelRequest changes the level of a named logger, the empty name is the root logger.
+type LogLevelRequest struct {
+	Name  string `json:"name"`
+	Level string `json:"level" binding:"required"`
+	// TTL reverts the level once exceeded, e.g. 10m, empty keeps the level.
+	TTL string `json:"ttl"`
+}
+
+// LogLevelResponse is the level of a named logger.
+type LogLevelResponse struct {
+	Name     string     `json:"name"`
+	Level    string     `json:"level"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// adminHandler is a handler mounted with the admin handlers.
+type adminHandler struct {
+	path    string
+	handler http.Handler
+}
+
+// registerAdmin mounts the admin handlers protected by token.
+func registerAdmin(g *gin.Engine, token string, handlers ...adminHandler) {
+	admin := g.Group("", adminAuth(token))
+	admin.GET(AdminLogLevelPath, getLogLevel)
+	admin.PUT(AdminLogLevelPath, putLogLevel)
+	for _, h := range handlers {
+		admin.GET(h.path, gin.WrapH(h.handler))
+	}
+}
+
+// adminAuth rejects requests without the admin token.
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader(AdminTokenHeader)
+		if got == "" {
+			got = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func getLogLevel(c *gin.Context) {
+	name := c.Query("name")
+	level, revertAt, err := logger.LevelOf(name)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newLogLevelResponse(name, level, revertAt))
+}
+
+func putLogLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	revertAt, err := logger.SetLevelFor(req.Name, level, ttl)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+	logger.Warnf("[HTTP] admin changed logger %q level to %s ttl %s from %s", req.Name, level, ttl, c.ClientIP())
+	c.JSON(http.StatusOK, newLogLevelResponse(req.Name, level, revertAt))
+}
+
+func adminError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, logger.ErrLoggerNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+func newLogLevelResponse(name string, level logger.Level, revertAt time.Time) LogLevelResponse {
+	resp := LogLevelResponse{Name: name, Level: level.String()}
+	if !revertAt.IsZero() {
+		resp.RevertAt = &revertAt
+	}
+	return resp
+}
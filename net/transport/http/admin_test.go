@@ -0,0 +1,109 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+)
+
+func TestAdminLogLevel(t *testing.T) {
+	defer logger.SetLevel(logger.InfoLevel)
+	g := gin.New()
+	registerAdmin(g, "secret")
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		token     string
+		wantCode  int
+		wantLevel string
+	}{
+		{
+			name:     "missing token",
+			method:   http.MethodGet,
+			target:   AdminLogLevelPath,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "put level with ttl",
+			method:    http.MethodPut,
+			target:    AdminLogLevelPath,
+			body:      `{"level": "debug", "ttl": "1m"}`,
+			token:     "secret",
+			wantCode:  http.StatusOK,
+			wantLevel: "debug",
+		},
+		{
+			name:      "get level",
+			method:    http.MethodGet,
+			target:    AdminLogLevelPath,
+			token:     "secret",
+			wantCode:  http.StatusOK,
+			wantLevel: "debug",
+		},
+		{
+			name:     "invalid level",
+			method:   http.MethodPut,
+			target:   AdminLogLevelPath,
+			body:     `{"level": "verbose"}`,
+			token:    "secret",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown logger",
+			method:   http.MethodGet,
+			target:   AdminLogLevelPath + "?name=unknown",
+			token:    "secret",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d, body %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantLevel == "" {
+				return
+			}
+			var resp LogLevelResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Level != tt.wantLevel || resp.RevertAt == nil {
+				t.Errorf("response = %+v, want level %s with revert_at", resp, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	g := gin.New()
+	registerAdmin(g, "secret", adminHandler{path: "/debug/test", handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("debug"))
+	})})
+	for _, token := range []string{"", "secret"} {
+		req := httptest.NewRequest(http.MethodGet, "/debug/test", nil)
+		req.Header.Set(AdminTokenHeader, token)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		wantCode := http.StatusUnauthorized
+		if token != "" {
+			wantCode = http.StatusOK
+		}
+		if w.Code != wantCode {
+			t.Fatalf("token %q code = %d, want %d", token, w.Code, wantCode)
+		}
+	}
+}
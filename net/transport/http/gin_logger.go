@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -9,7 +10,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/segmentio/ksuid"
 	"github.com/weiqiangxu/micro_project/common-config/logger"
+	appNet "github.com/weiqiangxu/micro_project/net"
 
 	"github.com/gin-gonic/gin"
 )
@@ -42,13 +45,13 @@ func GinZapWithConfig(conf *GinLoggerConfig) gin.HandlerFunc {
 			messages := []interface{}{
 				"method", c.Request.Method,
 				"status", c.Writer.Status(),
-				"query", query,
+				"query", logger.RedactQuery(query),
 				"ip", c.ClientIP(),
 				"user-agent", c.Request.UserAgent(),
 				"latency", latency,
 				"time", end.Format(conf.TimeFormat),
 			}
-			logger.Infow(path, messages...)
+			logger.Ctx(c.Request.Context()).Infow(path, messages...)
 		}
 	}
 }
@@ -74,13 +77,13 @@ func RecoveryWithZap(stack bool) gin.HandlerFunc {
 						}
 					}
 				}
-				httpRequest, _ := httputil.DumpRequest(c.Request, false)
+				httpRequest := dumpRequest(c.Request)
 				if brokenPipe {
 					message := []interface{}{
 						"error", err,
-						"request", string(httpRequest),
+						"request", httpRequest,
 					}
-					logger.Errorw(c.Request.URL.Path, message...)
+					logger.Ctx(c.Request.Context()).Errorw(c.Request.URL.Path, message...)
 					// If the connection is dead, we can't write a status to it.
 					e := c.Error(err.(error))
 					if e != nil {
@@ -93,17 +96,17 @@ func RecoveryWithZap(stack bool) gin.HandlerFunc {
 					message := []interface{}{
 						"time", time.Now(),
 						"error", err,
-						"request", string(httpRequest),
+						"request", httpRequest,
 						"stack", debug.Stack(),
 					}
-					logger.Errorw("[Recovery from panic]", message...)
+					logger.Ctx(c.Request.Context()).Errorw("[Recovery from panic]", message...)
 				} else {
 					message := []interface{}{
 						"time", time.Now(),
 						"error", err,
-						"request", string(httpRequest),
+						"request", httpRequest,
 					}
-					logger.Errorw("[Recovery from panic]", message...)
+					logger.Ctx(c.Request.Context()).Errorw("[Recovery from panic]", message...)
 				}
 				c.AbortWithStatus(http.StatusInternalServerError)
 			}
@@ -111,3 +114,43 @@ func RecoveryWithZap(stack bool) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// dumpRequest dumps the request line and headers, the sensitive headers, query
+// parameters and values are redacted by the rules of the logger.
+func dumpRequest(r *http.Request) string {
+	clone := r.Clone(r.Context())
+	clone.Header = logger.RedactHeader(r.Header)
+	clone.URL.RawQuery = logger.RedactQuery(r.URL.RawQuery)
+	clone.RequestURI = clone.URL.RequestURI()
+	dump, _ := httputil.DumpRequest(clone, false)
+	return logger.Redact(string(dump))
+}
+
+// RequestIDHeader is the header of the request id.
+const RequestIDHeader = "X-Request-Id"
+
+// ContextFields returns a gin.HandlerFunc carrying the request scoped log fields in the
+// request context: the app info of appCtx, the request id, the method and the route.
+// A request without X-Request-Id gets a new one, echoed in the response header.
+func ContextFields(appCtx func() context.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if app := appCtx(); app != nil {
+			if info, ok := appNet.FromContext(app); ok {
+				ctx = appNet.NewContext(ctx, info)
+			}
+		}
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = ksuid.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+		ctx = logger.WithFields(ctx,
+			"request_id", requestID,
+			"http_method", c.Request.Method,
+			"http_route", c.FullPath(),
+		)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
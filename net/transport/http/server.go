@@ -2,9 +2,13 @@ package http
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
+	"github.com/weiqiangxu/micro_project/net/tool"
 	"github.com/weiqiangxu/micro_project/net/transport"
 	"github.com/weiqiangxu/micro_project/user/config"
 
@@ -23,19 +27,32 @@ import (
 const (
 	DefaultHttpNetwork = "tcp"
 	DefaultHttpAddress = ":0"
+	SchemeOfHttp       = "http"
 )
 
-var _ transport.Server = (*Server)(nil)
+var (
+	_ transport.Server         = (*Server)(nil)
+	_ transport.Endpointer     = (*Server)(nil)
+	_ transport.ListenerCloser = (*Server)(nil)
+)
 
 type Server struct {
+	ctx           context.Context
+	mu            sync.RWMutex
 	gin           *gin.Engine
 	httpServer    *http.Server
+	listener      net.Listener
+	once          sync.Once
+	err           error
+	endpoint      *url.URL
 	address       string
 	network       string
 	handlersChain []gin.HandlerFunc
 	prometheus    bool
 	profile       bool
 	tracing       bool
+	adminToken    string
+	adminHandlers []adminHandler
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -62,6 +79,7 @@ func NewServer(opts ...ServerOption) *Server {
 	if srv.tracing {
 		g.Use(otelgin.Middleware(config.Conf.Application.Name))
 	}
+	g.Use(ContextFields(srv.appContext))
 	if len(srv.handlersChain) > 0 {
 		g.Use(srv.handlersChain...)
 	}
@@ -74,7 +92,14 @@ func NewServer(opts ...ServerOption) *Server {
 	g.GET("/healthC", func(c *gin.Context) {
 		c.JSON(http.StatusOK, http.StatusText(http.StatusOK))
 	})
+	if srv.adminToken != "" {
+		registerAdmin(g, srv.adminToken, srv.adminHandlers...)
+	}
 	srv.gin = g
+	srv.httpServer = &http.Server{
+		Addr:    srv.address,
+		Handler: g,
+	}
 	return srv
 }
 
@@ -84,19 +109,68 @@ func (s *Server) Server() *gin.Engine {
 }
 
 func (s *Server) Start(ctx context.Context) error {
-	srv := &http.Server{
-		Addr:    s.address,
-		Handler: s.gin,
+	if _, err := s.Endpoint(); err != nil {
+		return err
 	}
-	s.httpServer = srv
-	logger.Infof("[HTTP] server listening on: %s", s.address)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	logger.Infof("[HTTP] server listening on: %s", s.listener.Addr().String())
+	if err := s.httpServer.Serve(s.listener); err != nil && err != http.ErrServerClosed {
 		return err
 	}
 	return nil
 }
 
+// Stop gracefully shuts down the server, it is force closed once ctx is done.
 func (s *Server) Stop(ctx context.Context) error {
 	logger.Info("[HTTP] server stopping")
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil && ctx.Err() != nil {
+		logger.Warn("[HTTP] server graceful shutdown timeout, force closing")
+		if closeErr := s.httpServer.Close(); closeErr != nil {
+			logger.Errorf("[HTTP] close server catch err=%v", closeErr)
+		}
+	}
+	return err
+}
+
+// appContext returns the context the server started with.
+func (s *Server) appContext() context.Context {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ctx
+}
+
+// CloseListener closes the listener bound by Endpoint when the server is not started.
+func (s *Server) CloseListener() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// Endpoint binds the listener and returns the real endpoint of the server.
+func (s *Server) Endpoint() (*url.URL, error) {
+	s.once.Do(func() {
+		lis, err := net.Listen(s.network, s.address)
+		if err != nil {
+			s.err = err
+			return
+		}
+		addr, err := tool.Extract(s.address, lis)
+		if err != nil {
+			if err := lis.Close(); err != nil {
+				logger.Errorf("close %s listener catch err=%v", s.address, err)
+			}
+			s.err = err
+			return
+		}
+		s.listener = lis
+		s.endpoint = &url.URL{Scheme: SchemeOfHttp, Host: addr}
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.endpoint, nil
 }
@@ -1,6 +1,10 @@
 package http
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
 
 type ServerOption func(*Server)
 
@@ -33,3 +37,18 @@ func WithTracing(tracing bool) ServerOption {
 		server.tracing = tracing
 	}
 }
+
+// WithAdmin mounts the admin handlers protected by token, an empty token disables them.
+func WithAdmin(token string) ServerOption {
+	return func(server *Server) {
+		server.adminToken = token
+	}
+}
+
+// WithAdminHandler mounts a GET debug handler with the admin handlers, protected by the
+// admin token, e.g. WithAdminHandler(grpc_pool.DebugPath, grpc_pool.DebugHandler()).
+func WithAdminHandler(path string, handler http.Handler) ServerOption {
+	return func(server *Server) {
+		server.adminHandlers = append(server.adminHandlers, adminHandler{path: path, handler: handler})
+	}
+}
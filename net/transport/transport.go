@@ -1,8 +1,24 @@
 package transport
 
-import "context"
+import (
+	"context"
+	"net/url"
+)
 
 type Server interface {
 	Start(context.Context) error
 	Stop(context.Context) error
 }
+
+// Endpointer is implemented by servers that bind a listener before Start.
+// Endpoint binds the listener once and returns the real address it is bound to,
+// after it returns successfully Start serves on that listener.
+type Endpointer interface {
+	Endpoint() (*url.URL, error)
+}
+
+// ListenerCloser is implemented by Endpointers able to close the listener bound by Endpoint
+// when the server is never started, e.g. another server of its phase failed to bind.
+type ListenerCloser interface {
+	CloseListener() error
+}
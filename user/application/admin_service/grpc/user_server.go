@@ -2,14 +2,21 @@ package grpc
 
 import (
 	"context"
+	"github.com/weiqiangxu/micro_project/common-config/errors"
 	"github.com/weiqiangxu/micro_project/common-config/logger"
+	transportGrpc "github.com/weiqiangxu/micro_project/net/transport/grpc"
 	"time"
 
-	"github.com/pkg/errors"
 	redisApi "github.com/weiqiangxu/micro_project/common-config/cache"
 	"github.com/weiqiangxu/micro_project/protocol/user"
 )
 
+func init() {
+	// 请求参数在进入handler之前由校验拦截器校验, 不合法时返回InvalidArgument
+	transportGrpc.RegisterRules(&user.GetUserInfoRequest{}, map[string]string{"UniqueId": "required"})
+	transportGrpc.RegisterRules(&user.DeleteUserRequest{}, map[string]string{"Id": "gt=0"})
+}
+
 type UserAppGrpcOption func(service *UserAppGrpcService)
 
 func WithRedisApi(m *redisApi.RedisApi) UserAppGrpcOption {
@@ -33,7 +40,7 @@ func NewUserAppGrpcService(options ...UserAppGrpcOption) *UserAppGrpcService {
 
 func (srv *UserAppGrpcService) GetUserInfo(ctx context.Context, request *user.GetUserInfoRequest) (*user.GetUserInfoResponse, error) {
 	if request == nil {
-		return nil, errors.New("request is nil")
+		return nil, errors.ErrInvalidParams.WithMessage("request is nil")
 	}
 	// 延迟20秒钟调试GRPC连接此时的状态变化
 	time.Sleep(time.Second * 2)
@@ -13,6 +13,8 @@ import (
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
+	common "github.com/weiqiangxu/micro_project/common-config"
+	xerrors "github.com/weiqiangxu/micro_project/common-config/errors"
 	"github.com/weiqiangxu/micro_project/common-config/logger"
 
 	"github.com/weiqiangxu/micro_project/user/application/front_service/dtos"
@@ -101,8 +103,15 @@ func (m *UserAppHttpService) GetUserInfo(c *gin.Context) {
 		NameSub:  "3",
 	})
 	//child.Finish()
+	if errors.Is(err, xerrors.ErrBreakerOpen) {
+		// 用户服务熔断时不再等待, 降级返回默认的用户信息
+		logger.Ctx(c.Request.Context()).Warnf("user grpc service degraded catch err=%v", err)
+		c.JSON(http.StatusOK, &pbUser.UserInfo{})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusOK, err)
+		// RPC返回的错误码和i18n key原样返回给前端
+		common.ResponseFail(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, response.UserInfo)
@@ -11,11 +11,14 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	jaegerConfig "github.com/uber/jaeger-client-go/config"
+	googleGrpc "google.golang.org/grpc"
 
 	redisApi "github.com/weiqiangxu/micro_project/common-config/cache"
 	"github.com/weiqiangxu/micro_project/common-config/logger"
+	"github.com/weiqiangxu/micro_project/net/registry"
 	"github.com/weiqiangxu/micro_project/net/transport"
 	"github.com/weiqiangxu/micro_project/net/transport/grpc"
+	grpcPool "github.com/weiqiangxu/micro_project/net/transport/grpc_pool"
 	pbUser "github.com/weiqiangxu/micro_project/protocol/user"
 	adminGrpc "github.com/weiqiangxu/micro_project/user/application/admin_service/grpc"
 	"github.com/weiqiangxu/micro_project/user/application/event"
@@ -51,40 +54,74 @@ func Init() {
 	var loginClient pbUser.LoginClient
 	if !reflect.DeepEqual(config.Conf.UserGrpcConfig, format.GrpcConfig{}) {
 		// 如果是客户端才需要连接
-		userGrpcConn, err := grpc.Dial(
-			context.Background(),
+		userGrpcConfig := config.Conf.UserGrpcConfig
+		dialOpts := []grpc.ClientOption{
 			grpc.WithInSecure(true),
-			grpc.WithEndpoint(config.Conf.UserGrpcConfig.Addr),
+			grpc.WithEndpoint(userGrpcConfig.Addr),
 			grpc.WithTracing(true),
 			grpc.WithPrometheus(true),
 			grpc.WithUnaryTraceInterceptor(tracer),
-		)
-		if err != nil {
-			logger.Fatal(err)
+			// 服务端暂时不可用时退避重试, 重试预算避免服务端故障时重试风暴
+			grpc.WithTimeout(5 * time.Second),
+			grpc.WithRetry("*", grpc.DefaultRetryPolicy()),
+			grpc.WithRetryBudget(grpc.DefaultRetryBudget()),
+			// 用户服务变慢或故障时熔断, HTTP接口降级返回, 连接池的连接共享同一组熔断器和重试预算
+			grpc.WithCircuitBreaker(grpc.DefaultBreakerConfig()),
 		}
-		// 创建一个定时器，设置时间间隔为5秒（可根据需求修改）
-		ticker := time.NewTicker(60 * time.Second)
-		// 使用for循环来持续接收定时器的触发事件
-		go func() {
-			for range ticker.C {
-				logger.Info("当前GRPC连接状态:", userGrpcConn.GetState().String())
+		// 配置了CA证书时开启TLS, 同时配置了客户端证书时为mTLS
+		if userGrpcConfig.CAFile != "" {
+			dialOpts = append(dialOpts, grpc.WithCA(userGrpcConfig.CAFile), grpc.WithServerName(userGrpcConfig.ServerName))
+		}
+		if userGrpcConfig.CertFile != "" {
+			dialOpts = append(dialOpts, grpc.WithClientCert(userGrpcConfig.CertFile, userGrpcConfig.KeyFile))
+		}
+		// 配置了注册中心时通过 discovery:///<service name> 发现服务地址
+		if config.Conf.RegistryConfig.File != "" {
+			dialOpts = append(dialOpts, grpc.WithDiscovery(registry.NewFile(config.Conf.RegistryConfig.File)))
+		}
+		if userGrpcConfig.PoolMaxCap > 0 {
+			// 每次RPC调用从GRPC连接池租用一个连接, 请求完成时释放连接
+			poolMode, err := grpcPool.ParseMode(userGrpcConfig.PoolMode)
+			if err != nil {
+				logger.Fatal(err)
 			}
-		}()
-
-		// TODO 获取userGrpcConn的方式更改为GRPC连接池获取
-		// userGrpcConn := grpcPool.Get()
-		// TODO grpc连接池获取连接的时候怎么判断这个连接目前是空闲的呢,请求发起到请求完成有事件吗
-		// (拦截器的defer是可以接收到完成)
-		// TODO grpc连接池获取连接的时候这个连接的顺序是怎么保证的呢
-
-		// TODO 连接池代码和接口调用代码的耦合比较严重,因为连接没有状态标识是否空闲
-		// TODO 可以把 Dial时候传入pool的key 在请求完成的事件将 key通知到 pool从而释放连接
-		// 请求完成的事件放在拦截器的 defer 事件之中
-		// currentConn := pool.GetGrpcConn() // 计数器+1
-		// pbUser.NewLoginClient(currentConn.GetConn()).ListUser() // RPC调用获取数据
-		// defer currentConn.Release() // 释放回GRPC连接池
-
-		loginClient = pbUser.NewLoginClient(userGrpcConn)
+			userGrpcPool, err := grpcPool.New(&grpcPool.Config{
+				Name:        "user",
+				Mode:        poolMode,
+				InitialCap:  max(userGrpcConfig.PoolInitialCap, 1),
+				MaxCap:      userGrpcConfig.PoolMaxCap,
+				MaxIdle:     userGrpcConfig.PoolMaxIdle,
+				IdleTimeout: 10 * time.Minute,
+				WaitTimeout: time.Second,
+				Factory: func() (*googleGrpc.ClientConn, error) {
+					return grpc.Dial(context.Background(), dialOpts...)
+				},
+			})
+			if err != nil {
+				logger.Fatal(err)
+			}
+			ticker := time.NewTicker(60 * time.Second)
+			go func() {
+				for range ticker.C {
+					logger.Infof("当前GRPC连接池状态: %+v", userGrpcPool.Stats())
+				}
+			}()
+			loginClient = pbUser.NewLoginClient(userGrpcPool)
+		} else {
+			userGrpcConn, err := grpc.Dial(context.Background(), dialOpts...)
+			if err != nil {
+				logger.Fatal(err)
+			}
+			// 创建一个定时器，设置时间间隔为5秒（可根据需求修改）
+			ticker := time.NewTicker(60 * time.Second)
+			// 使用for循环来持续接收定时器的触发事件
+			go func() {
+				for range ticker.C {
+					logger.Info("当前GRPC连接状态:", userGrpcConn.GetState().String())
+				}
+			}()
+			loginClient = pbUser.NewLoginClient(userGrpcConn)
+		}
 	}
 
 	// inject rpc client && redis into domain service
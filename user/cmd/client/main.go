@@ -1,10 +1,12 @@
 package main
 
 import (
+	"os"
+
 	"github.com/weiqiangxu/micro_project/common-config/format"
 	"github.com/weiqiangxu/micro_project/common-config/logger"
 	"github.com/weiqiangxu/micro_project/net"
-	"github.com/weiqiangxu/micro_project/net/transport"
+	grpcPool "github.com/weiqiangxu/micro_project/net/transport/grpc_pool"
 	"github.com/weiqiangxu/micro_project/net/transport/http"
 	"github.com/weiqiangxu/micro_project/user/application"
 	"github.com/weiqiangxu/micro_project/user/config"
@@ -12,11 +14,12 @@ import (
 )
 
 func main() {
-	// inject config from nacos
+	// 配置默认值, 可被配置文件、环境变量、nacos覆盖
 	config.Conf = config.Config{
 		Application:     config.AppInfo{Name: "admin", Version: "v0.0.2"},
 		HttpConfig:      format.HttpConfig{ListenHTTP: ":8181", Prometheus: true},
-		UserGrpcConfig:  format.GrpcConfig{Addr: ":9191"},
+		UserGrpcConfig:  format.GrpcConfig{Addr: "discovery:///server"},
+		RegistryConfig:  format.RegistryConfig{File: "/tmp/micro_project_registry.json"},
 		OrderGrpcConfig: format.GrpcConfig{},
 		LogConfig:       format.LogConfig{},
 		WikiMongoDb:     format.MongoConfig{},
@@ -29,6 +32,16 @@ func main() {
 			Addr: "http://127.0.0.1:14268/api/traces",
 		},
 	}
+	// 加载并校验配置
+	if err := config.Init(os.Args[0], os.Args[1:]); err != nil {
+		logger.Fatal(err)
+	}
+	// 按配置输出日志, 文件输出支持按大小和时间切割
+	logConfig, err := logger.NewConfigFromLogConfig(config.Conf.LogConfig)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	logger.SetConfig(logConfig)
 	application.Init()
 	// 注册Http服务监听地址
 	// 注入Prometheus指标采集地址
@@ -36,21 +49,20 @@ func main() {
 	httpServer := http.NewServer(
 		http.WithAddress(config.Conf.HttpConfig.ListenHTTP),
 		http.WithPrometheus(config.Conf.HttpConfig.Prometheus),
-		http.WithProfile(config.Conf.HttpConfig.Profile))
+		http.WithProfile(config.Conf.HttpConfig.Profile),
+		// 配置了admin token时挂载运行时修改日志级别的接口和GRPC连接池的调试接口
+		http.WithAdmin(config.Conf.AdminConfig.Token),
+		http.WithAdminHandler(grpcPool.DebugPath, grpcPool.DebugHandler()))
 	// 挂载路由到服务中
 	router.Init(httpServer.Server())
 	// 注入Prometheus指标采集的拦截器
 	router.RegisterPrometheus()
-	// 注册HTTP服务 && RPC服务 到Gin引擎(start/stop的实现)
-	serverList := []transport.Server{httpServer}
-	if len(application.App.Event) > 0 {
-		serverList = append(serverList, application.App.Event...)
-	}
-	// 统一应用管理
+	// 统一应用管理: 先启动事件消费再启动HTTP监听, 停止时按相反顺序
 	app := net.New(
 		net.Name(config.Conf.Application.Name),
 		net.Version(config.Conf.Application.Version),
-		net.Server(serverList...),
+		net.Phase("event", application.App.Event...),
+		net.Phase("http", httpServer),
 	)
 	if err := app.Run(); err != nil {
 		logger.Fatal(err)
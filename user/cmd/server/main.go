@@ -1,10 +1,13 @@
 package main
 
 import (
+	"os"
+	"time"
+
 	"github.com/weiqiangxu/micro_project/common-config/format"
 	"github.com/weiqiangxu/micro_project/common-config/logger"
 	"github.com/weiqiangxu/micro_project/net"
-	"github.com/weiqiangxu/micro_project/net/transport"
+	"github.com/weiqiangxu/micro_project/net/registry"
 	"github.com/weiqiangxu/micro_project/net/transport/grpc"
 	"github.com/weiqiangxu/micro_project/protocol/user"
 	"github.com/weiqiangxu/micro_project/user/application"
@@ -13,27 +16,55 @@ import (
 )
 
 func main() {
-	// 配置依赖注入
+	// 配置默认值, 可被配置文件、环境变量、nacos覆盖
 	config.Conf = config.Config{
 		Application:          config.AppInfo{Name: "server", Version: "v0.0.1"},
 		UserGrpcServerConfig: format.GrpcConfig{Addr: ":9191"},
+		RegistryConfig:       format.RegistryConfig{File: "/tmp/micro_project_registry.json"},
+	}
+	// 加载并校验配置
+	if err := config.Init(os.Args[0], os.Args[1:]); err != nil {
+		logger.Fatal(err)
+	}
+	// 按配置输出日志, 文件输出支持按大小和时间切割
+	logConfig, err := logger.NewConfigFromLogConfig(config.Conf.LogConfig)
+	if err != nil {
+		logger.Fatal(err)
 	}
+	logger.SetConfig(logConfig)
 	// mongodb && redis 等服务依赖
 	application.Init()
 	router.RegisterPrometheus()
 	// 注入GRPC服务启动时候的监听地址
-	grpcServer := grpc.NewServer(grpc.Address(config.Conf.UserGrpcServerConfig.Addr))
+	// 配置了admin token时注册运行时修改日志级别的admin服务
+	serverOpts := []grpc.ServerOption{
+		grpc.Address(config.Conf.UserGrpcServerConfig.Addr),
+		grpc.Admin(config.Conf.AdminConfig.Token),
+		grpc.Tracing(true),
+		grpc.Recovery(true),
+		// 自适应并发限制, 延迟升高时拒绝超出上限的请求
+		grpc.ConcurrencyLimit(grpc.DefaultConcurrencyLimitConfig()),
+	}
+	// 配置了证书时开启TLS, 同时配置了CA证书时校验客户端证书(mTLS)
+	if serverConfig := config.Conf.UserGrpcServerConfig; serverConfig.CertFile != "" {
+		serverOpts = append(serverOpts, grpc.TLS(serverConfig.CertFile, serverConfig.KeyFile))
+		if serverConfig.CAFile != "" {
+			serverOpts = append(serverOpts, grpc.ClientCA(serverConfig.CAFile))
+		}
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
 	// 将获取用户信息的接口实现注入GRPC服务
 	user.RegisterLoginServer(grpcServer, application.App.AdminService.UserGrpcService)
-	serverList := []transport.Server{grpcServer}
-	if len(application.App.Event) > 0 {
-		serverList = append(serverList, application.App.Event...)
-	}
 	// 将grpc && http 服务注入应用
+	// 先启动事件消费再启动GRPC监听, 停止时按相反顺序
 	app := net.New(
 		net.Name(config.Conf.Application.Name),
 		net.Version(config.Conf.Application.Version),
-		net.Server(serverList...),
+		// 启动完成注册GRPC地址, 停止前注销
+		net.Registrar(registry.NewFile(config.Conf.RegistryConfig.File)),
+		net.Phase("event", application.App.Event...),
+		net.Phase("grpc", grpcServer),
+		net.ServerStopTimeout(15*time.Second, grpcServer),
 	)
 	if err := app.Run(); err != nil {
 		logger.Fatal(err)
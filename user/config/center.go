@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/weiqiangxu/micro_project/common-config/config_center"
+	"github.com/weiqiangxu/micro_project/common-config/logger"
+)
+
+// Center 配置中心, Load之后可以监听配置的变更
+var Center *config_center.Config
+
+// Load 按顺序加载配置源(文件、环境变量、nacos)到 Conf, 后面的配置源覆盖前面的
+// 并监听日志级别的变更, 无需重启即可生效
+func Load(sources ...config_center.Source) error {
+	c := config_center.New(config_center.WithSource(sources...))
+	if err := c.Load(); err != nil {
+		return err
+	}
+	if err := c.Scan(&Conf); err != nil {
+		_ = c.Close()
+		return err
+	}
+	config_center.OnChange(c, "log_config.level", func(level string) {
+		lvl, err := logger.ParseLevel(strings.TrimSpace(level))
+		if err != nil {
+			logger.Errorf("[config] change log level catch err=%v", err)
+			return
+		}
+		logger.SetLevel(lvl)
+		logger.Infof("[config] log level changed to %s", lvl)
+	})
+	Center = c
+	return nil
+}
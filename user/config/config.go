@@ -7,20 +7,23 @@ import (
 var Conf Config
 
 type Config struct {
-	Application          AppInfo            `toml:"application" json:"application"`
-	HttpConfig           format.HttpConfig  `toml:"http_config" json:"http_config"`
-	UserGrpcConfig       format.GrpcConfig  `toml:"user_grpc_config" json:"user_grpc_config"`
-	UserGrpcServerConfig format.GrpcConfig  `toml:"user_grpc_server_config" json:"user_grpc_server_config"`
-	OrderGrpcConfig      format.GrpcConfig  `toml:"order_grpc_config" json:"order_grpc_config"`
-	LogConfig            format.LogConfig   `toml:"log_config" json:"log_config"`
-	WikiMongoDb          format.MongoConfig `toml:"wiki_mongo_db" json:"wiki_mongo_db"`
-	WikiRedisDb          format.RedisConfig `toml:"wiki_redis_db" json:"wiki_redis_db"`
-	JwtConfig            JwtConfig          `toml:"jwt_config" json:"jwt_config"`
-	JaegerConfig         JaegerConfig       `toml:"jaeger_config" json:"jaeger_config"`
+	Application          AppInfo               `toml:"application" json:"application"`
+	HttpConfig           format.HttpConfig     `toml:"http_config" json:"http_config" validate:"omitempty"`
+	UserGrpcConfig       format.GrpcConfig     `toml:"user_grpc_config" json:"user_grpc_config" validate:"omitempty"`
+	UserGrpcServerConfig format.GrpcConfig     `toml:"user_grpc_server_config" json:"user_grpc_server_config" validate:"omitempty"`
+	OrderGrpcConfig      format.GrpcConfig     `toml:"order_grpc_config" json:"order_grpc_config" validate:"omitempty"`
+	RegistryConfig       format.RegistryConfig `toml:"registry_config" json:"registry_config"`
+	NacosConfig          format.NacosConfig    `toml:"nacos_config" json:"nacos_config"`
+	LogConfig            format.LogConfig      `toml:"log_config" json:"log_config"`
+	AdminConfig          format.AdminConfig    `toml:"admin_config" json:"admin_config"`
+	WikiMongoDb          format.MongoConfig    `toml:"wiki_mongo_db" json:"wiki_mongo_db" validate:"omitempty"`
+	WikiRedisDb          format.RedisConfig    `toml:"wiki_redis_db" json:"wiki_redis_db" validate:"omitempty"`
+	JwtConfig            JwtConfig             `toml:"jwt_config" json:"jwt_config"`
+	JaegerConfig         JaegerConfig          `toml:"jaeger_config" json:"jaeger_config"`
 }
 
 type JaegerConfig struct {
-	Addr string `toml:"addr"`
+	Addr string `toml:"addr" validate:"omitempty,url"`
 }
 
 type JwtConfig struct {
@@ -29,6 +32,6 @@ type JwtConfig struct {
 }
 
 type AppInfo struct {
-	Name    string `toml:"name"`
+	Name    string `toml:"name" validate:"required"`
 	Version string `toml:"version"`
 }
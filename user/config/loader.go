@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/weiqiangxu/micro_project/common-config/config_center"
+)
+
+// DefaultEnvPrefix 默认环境变量前缀, 例如 USER_HTTP_CONFIG__LISTEN_HTTP=:8181
+const DefaultEnvPrefix = "USER_"
+
+// Init 解析命令行参数, 依次叠加 -conf 指定的toml/json配置文件、环境变量、nacos配置到 Conf
+// 调用前 Conf 中的值作为默认值, 校验不通过时返回所有不合法的字段
+func Init(name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := fs.String("conf", "", "config file path, toml or json")
+	prefix := fs.String("env_prefix", DefaultEnvPrefix, "prefix of the config environment variables")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var sources []config_center.Source
+	if *path != "" {
+		sources = append(sources, config_center.NewFileSource(*path))
+	}
+	sources = append(sources, config_center.NewEnvSource(*prefix))
+	if err := Load(sources...); err != nil {
+		return err
+	}
+	// 文件和环境变量中配置了nacos时再叠加nacos中的配置
+	if Conf.NacosConfig.Host != "" {
+		_ = Center.Close()
+		sources = append(sources, config_center.NewNacosSource(Conf.NacosConfig))
+		if err := Load(sources...); err != nil {
+			return err
+		}
+	}
+	return Validate(Conf)
+}
+
+// Validate 按字段的 validate 标签校验配置, 返回的错误包含所有不合法的字段
+func Validate(c Config) error {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	err := v.Struct(c)
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	lines := make([]string, 0, len(errs))
+	for _, e := range errs {
+		// 去掉命名空间中的结构体名, 与配置文件中的key保持一致
+		_, key, _ := strings.Cut(e.Namespace(), ".")
+		lines = append(lines, fmt.Sprintf("  %s: %s, got %q", key, invalidReason(e), fmt.Sprint(e.Value())))
+	}
+	return fmt.Errorf("invalid config:\n%s", strings.Join(lines, "\n"))
+}
+
+// invalidReason 校验标签对应的可读描述
+func invalidReason(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "hostname_port":
+		return "must be host:port"
+	case "hostname_port|uri":
+		return "must be host:port or an uri"
+	case "url":
+		return "must be an url"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(e.Param(), " ", "|")
+	case "ascii":
+		return "must be ascii"
+	}
+	if e.Param() != "" {
+		return fmt.Sprintf("must satisfy %s=%s", e.Tag(), e.Param())
+	}
+	return "must satisfy " + e.Tag()
+}
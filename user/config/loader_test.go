@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		env       map[string]string
+		wantErr   []string
+		wantHttp  string
+		wantLevel string
+	}{
+		{
+			name:      "file and env overlay defaults",
+			file:      "[application]\nname = \"admin\"\n\n[http_config]\nlisten_http = \":8181\"\n",
+			env:       map[string]string{"TEST_USER_LOG_CONFIG__LEVEL": "debug"},
+			wantHttp:  ":8181",
+			wantLevel: "debug",
+		},
+		{
+			name: "every invalid field is reported",
+			file: "[application]\nname = \"\"\n\n[http_config]\nlisten_http = \"8181\"\n\n[log_config]\nlevel = \"verbose\"\n",
+			wantErr: []string{
+				"application.name: is required",
+				"http_config.listen_http: must be host:port",
+				"log_config.level: must be one of debug|info|warn|error|fatal|panic",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Conf = Config{Application: AppInfo{Name: "default"}}
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			err := Init("test", []string{"-conf", path, "-env_prefix", "TEST_USER_"})
+			defer Center.Close()
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("Init() error = nil, want %v", tt.wantErr)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("Init() error = %v, want contains %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Init() error = %v", err)
+			}
+			if Conf.HttpConfig.ListenHTTP != tt.wantHttp || Conf.LogConfig.Level != tt.wantLevel {
+				t.Errorf("Init() Conf = %+v", Conf)
+			}
+		})
+	}
+}
@@ -3,7 +3,10 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weiqiangxu/micro_project/common-config/logger"
 	"github.com/weiqiangxu/micro_project/common-config/metrics"
+	"github.com/weiqiangxu/micro_project/net/transport/grpc"
+	grpcPool "github.com/weiqiangxu/micro_project/net/transport/grpc_pool"
 	"github.com/weiqiangxu/micro_project/user/application"
 	"github.com/weiqiangxu/micro_project/user/config"
 	"github.com/weiqiangxu/micro_project/user/global/pprof_tool"
@@ -34,4 +37,14 @@ func RegisterPrometheus() {
 	prometheus.MustRegister(metrics.RequestGauge)
 	prometheus.MustRegister(metrics.Counter)
 	prometheus.MustRegister(metrics.Summary)
+	prometheus.MustRegister(logger.AsyncDroppedCounter)
+	prometheus.MustRegister(logger.GormQueryDurationHistogram)
+	prometheus.MustRegister(logger.GormSlowQueryCounter)
+	prometheus.MustRegister(grpc.ServerShedCounter)
+	prometheus.MustRegister(grpc.ServerConcurrencyLimitGauge)
+	prometheus.MustRegister(grpc.ServerInflightGauge)
+	prometheus.MustRegister(grpc.ClientBreakerStateGauge)
+	prometheus.MustRegister(grpc.ClientBreakerRejectedCounter)
+	prometheus.MustRegister(grpcPool.Collector)
+	prometheus.MustRegister(grpcPool.WaitDurationHistogram)
 }